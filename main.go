@@ -3,10 +3,28 @@ package main
 import (
 	"api/src/config"
 	"api/src/database"
+	"api/src/events"
+	"api/src/health"
+	"api/src/live"
+	"api/src/mailer"
+	"api/src/media"
 	"api/src/router"
+	"api/src/socket"
+	"api/src/storage"
+	"api/src/stream"
+	"api/src/trending"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	zlog "github.com/rs/zerolog/log"
 
 	_ "api/docs"
 )
@@ -28,9 +46,154 @@ import (
 // @description Bearer JWT token authentication, type "Bearer {token}"
 // @tokenUrl /login
 func main() {
-	config.LoadEnvs()
-	database.Connect()
+	args := os.Args[1:]
+	if len(args) >= 2 && args[0] == "config" && args[1] == "print" {
+		os.Exit(printConfig(args[2:]))
+	}
+	if len(args) >= 2 && args[0] == "trending" && args[1] == "refresh" {
+		os.Exit(refreshTrending(args[2:]))
+	}
+
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+	configureLogging(cfg.Log)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if err := database.Open(ctx); err != nil {
+		log.Fatal(err)
+	}
+	m, err := mailer.New(cfg.Mail)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mailer.Configure(m)
+	bus, err := events.Open(cfg.Events)
+	if err != nil {
+		log.Fatal(err)
+	}
+	events.Configure(bus)
+	store, err := storage.New(cfg.Media)
+	if err != nil {
+		log.Fatal(err)
+	}
+	storage.Configure(store)
+	live.Start()
+	go events.Listen(ctx)
+
+	registerHealthChecks(cfg.Health)
+	go trending.Run(ctx, cfg.Trending)
+	go media.Run(ctx, cfg.Media)
 	r := router.GenerateRouter()
-	fmt.Println("API running on port 8080 with base path /api")
-	log.Fatal(http.ListenAndServe(":8080", r))
+
+	server := &http.Server{
+		Addr:              cfg.Server.Addr(),
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+	// Live streams never finish on their own, so end them when shutdown
+	// starts instead of waiting for the timeout.
+	server.RegisterOnShutdown(stream.Default().Shutdown)
+	server.RegisterOnShutdown(socket.Default().Shutdown)
+
+	serverErr := make(chan error, 1)
+	go func() {
+		fmt.Printf("API running on %s with base path /api\n", server.Addr)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+		return
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Fail readiness first and give load balancers time to notice before
+	// we stop accepting new connections.
+	fmt.Println("Shutdown signal received, draining")
+	health.SetDraining()
+	time.Sleep(cfg.Server.DrainDelay)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Graceful shutdown did not complete: %v", err)
+	}
+
+	database.Close()
+	fmt.Println("Server stopped")
+}
+
+// printConfig implements `config print`, showing the effective
+// configuration with secrets redacted followed by any validation errors.
+func printConfig(args []string) int {
+	cfg, err := config.Load(args)
+	if cfg != nil {
+		if printErr := config.Print(os.Stdout, *cfg); printErr != nil {
+			fmt.Fprintln(os.Stderr, printErr)
+			return 1
+		}
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// refreshTrending implements `trending refresh`, recomputing the trending
+// tags once without starting the server.
+func refreshTrending(args []string) int {
+	cfg, err := config.Load(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		return 1
+	}
+	configureLogging(cfg.Log)
+
+	ctx := context.Background()
+	if err := database.Open(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+
+	if err := trending.Refresh(ctx, cfg.Trending); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to refresh trending tags:\n%v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func registerHealthChecks(cfg config.HealthConfig) {
+	health.Configure(cfg.CheckTimeout, cfg.CacheTTL)
+	health.Register("database", health.CheckerFunc(database.Ping))
+	health.Register("migrations", health.CheckerFunc(database.CheckSchema))
+	health.Register("mailer", health.CheckerFunc(mailer.Check))
+	health.Register("storage", health.CheckerFunc(storage.Check))
+}
+
+func configureLogging(cfg config.LogConfig) {
+	level, err := zerolog.ParseLevel(cfg.Level)
+	if err != nil {
+		level = zerolog.InfoLevel
+	}
+	zerolog.SetGlobalLevel(level)
+
+	if cfg.Format == "console" {
+		zlog.Logger = zlog.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+	}
 }
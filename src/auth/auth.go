@@ -1,10 +1,10 @@
 package auth
 
 import (
+	"api/src/config"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
@@ -14,11 +14,11 @@ import (
 func GenerateToken(userID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(time.Hour * 24).Unix(),
+		"exp":     time.Now().Add(config.Get().Auth.TokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(os.Getenv("API_SECRET")))
+	return token.SignedString([]byte(config.Get().Auth.Secret))
 }
 
 func ValidateToken(r *http.Request) error {
@@ -32,7 +32,7 @@ func ValidateToken(r *http.Request) error {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return []byte(os.Getenv("API_SECRET")), nil
+		return []byte(config.Get().Auth.Secret), nil
 	})
 
 	if err != nil {
@@ -62,12 +62,39 @@ func ExtractUserID(r *http.Request) (string, error) {
 		return "", err
 	}
 
+	return ParseUserID(tokenString)
+}
+
+// TokenParam names the query parameter and cookie that may carry the JWT
+// for clients unable to set headers, such as browser EventSource.
+const TokenParam = "access_token"
+
+// ExtractUserIDAnywhere is ExtractUserID for long-lived connections. The
+// token may also come from the access_token query parameter or cookie.
+func ExtractUserIDAnywhere(r *http.Request) (string, error) {
+	if r.Header.Get("Authorization") != "" {
+		return ExtractUserID(r)
+	}
+
+	if tokenString := r.URL.Query().Get(TokenParam); tokenString != "" {
+		return ParseUserID(tokenString)
+	}
+
+	if cookie, err := r.Cookie(TokenParam); err == nil && cookie.Value != "" {
+		return ParseUserID(cookie.Value)
+	}
+
+	return "", errors.New("authorization header, access_token parameter or cookie is required")
+}
+
+// ParseUserID validates a JWT and returns the user it was issued to.
+func ParseUserID(tokenString string) (string, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return []byte(os.Getenv("API_SECRET")), nil
+		return []byte(config.Get().Auth.Secret), nil
 	})
 
 	if err != nil {
@@ -86,3 +113,19 @@ func ExtractUserID(r *http.Request) (string, error) {
 
 	return userID, nil
 }
+
+// IsAdmin reports whether the request carries a valid token for one of the
+// configured admin users.
+func IsAdmin(r *http.Request) bool {
+	userID, err := ExtractUserID(r)
+	if err != nil {
+		return false
+	}
+
+	for _, id := range config.Get().Auth.AdminIDs {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
@@ -1,33 +1,550 @@
 package config
 
 import (
-	"log"
-	"os"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
-	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
 )
 
+const redacted = "******"
+
+// BasePath is the path every API route is mounted under.
+const BasePath = "/api"
+
 var (
-	DatabaseUrl      = ""
-	DatabasePort     = ""
-	DatabaseHost     = ""
-	DatabaseName     = ""
-	DatabaseUser     = ""
-	DatabasePassword = ""
-	ApiSecret        = ""
+	reactionPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+	channelPattern  = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
 )
 
-func LoadEnvs() {
-	var err error
+type Config struct {
+	Server   ServerConfig   `yaml:"server" toml:"server"`
+	Database DatabaseConfig `yaml:"database" toml:"database"`
+	Auth     AuthConfig     `yaml:"auth" toml:"auth"`
+	Log      LogConfig      `yaml:"log" toml:"log"`
+	Health   HealthConfig   `yaml:"health" toml:"health"`
+	Posts    PostsConfig    `yaml:"posts" toml:"posts"`
+	Trending TrendingConfig `yaml:"trending" toml:"trending"`
+	Mail     MailConfig     `yaml:"mail" toml:"mail"`
+	Recovery RecoveryConfig `yaml:"recovery" toml:"recovery"`
+	Stream   StreamConfig   `yaml:"stream" toml:"stream"`
+	Socket   SocketConfig   `yaml:"socket" toml:"socket"`
+	Events   EventsConfig   `yaml:"events" toml:"events"`
+	Messages MessagesConfig `yaml:"messages" toml:"messages"`
+	Media    MediaConfig    `yaml:"media" toml:"media"`
+	Users    UsersConfig    `yaml:"users" toml:"users"`
+	Features FeaturesConfig `yaml:"features" toml:"features"`
+}
+
+type ServerConfig struct {
+	Port              string        `yaml:"port" toml:"port"`
+	ListenAddr        string        `yaml:"listen_addr" toml:"listen_addr"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ShutdownTimeout   time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	DrainDelay        time.Duration `yaml:"drain_delay" toml:"drain_delay"`
+}
+
+type DatabaseConfig struct {
+	URL      string `yaml:"url" toml:"url"`
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	Name     string `yaml:"name" toml:"name"`
+	User     string `yaml:"user" toml:"user"`
+	Password string `yaml:"password" toml:"password"`
+	MaxConns int    `yaml:"max_conns" toml:"max_conns"`
+
+	ConnectTimeout        time.Duration `yaml:"connect_timeout" toml:"connect_timeout"`
+	ConnectAttemptTimeout time.Duration `yaml:"connect_attempt_timeout" toml:"connect_attempt_timeout"`
+	RetryBackoff          time.Duration `yaml:"retry_backoff" toml:"retry_backoff"`
+	RetryMaxBackoff       time.Duration `yaml:"retry_max_backoff" toml:"retry_max_backoff"`
+	QueryAttempts         int           `yaml:"query_attempts" toml:"query_attempts"`
+	StatementTimeout      time.Duration `yaml:"statement_timeout" toml:"statement_timeout"`
+	SlowQueryThreshold    time.Duration `yaml:"slow_query_threshold" toml:"slow_query_threshold"`
+}
 
-	if err = godotenv.Load(); err != nil {
-		log.Fatal(err)
+type AuthConfig struct {
+	Secret   string        `yaml:"secret" toml:"secret"`
+	TokenTTL time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+	AdminIDs []string      `yaml:"admin_ids" toml:"admin_ids"`
+}
+
+type LogConfig struct {
+	Level  string `yaml:"level" toml:"level"`
+	Format string `yaml:"format" toml:"format"`
+}
+
+type HealthConfig struct {
+	CheckTimeout time.Duration `yaml:"check_timeout" toml:"check_timeout"`
+	CacheTTL     time.Duration `yaml:"cache_ttl" toml:"cache_ttl"`
+}
+
+type PostsConfig struct {
+	CommentMaxDepth int      `yaml:"comment_max_depth" toml:"comment_max_depth"`
+	Reactions       []string `yaml:"reactions" toml:"reactions"`
+}
+
+// TrendingConfig controls the trending tags job. Windows are durations
+// such as "24h" and name the windows clients can ask for.
+type TrendingConfig struct {
+	Interval      time.Duration `yaml:"interval" toml:"interval"`
+	Timeout       time.Duration `yaml:"timeout" toml:"timeout"`
+	HalfLife      time.Duration `yaml:"half_life" toml:"half_life"`
+	Windows       []string      `yaml:"windows" toml:"windows"`
+	DefaultWindow string        `yaml:"default_window" toml:"default_window"`
+}
+
+// MailConfig selects how emails are sent. The file transport writes each
+// message to Dir instead of sending it, which is handy in development.
+type MailConfig struct {
+	Transport    string `yaml:"transport" toml:"transport"`
+	From         string `yaml:"from" toml:"from"`
+	Dir          string `yaml:"dir" toml:"dir"`
+	SMTPHost     string `yaml:"smtp_host" toml:"smtp_host"`
+	SMTPPort     string `yaml:"smtp_port" toml:"smtp_port"`
+	SMTPUser     string `yaml:"smtp_user" toml:"smtp_user"`
+	SMTPPassword string `yaml:"smtp_password" toml:"smtp_password"`
+}
+
+// RecoveryConfig controls account recovery through the secondary email.
+// A recovery can be confirmed once Delay has passed and until TokenTTL
+// after that. TokenTTL also bounds email verification links.
+type RecoveryConfig struct {
+	Delay    time.Duration `yaml:"delay" toml:"delay"`
+	TokenTTL time.Duration `yaml:"token_ttl" toml:"token_ttl"`
+}
+
+// StreamConfig controls the live update stream.
+type StreamConfig struct {
+	Heartbeat             time.Duration `yaml:"heartbeat" toml:"heartbeat"`
+	ReplayBuffer          int           `yaml:"replay_buffer" toml:"replay_buffer"`
+	MaxConnectionsPerUser int           `yaml:"max_connections_per_user" toml:"max_connections_per_user"`
+}
+
+// SocketConfig controls the WebSocket gateway. Clients that do not answer
+// a ping within twice PingInterval, or let SendBuffer messages pile up,
+// are disconnected.
+type SocketConfig struct {
+	PingInterval time.Duration `yaml:"ping_interval" toml:"ping_interval"`
+	SendBuffer   int           `yaml:"send_buffer" toml:"send_buffer"`
+	MaxTopics    int           `yaml:"max_topics" toml:"max_topics"`
+}
+
+// EventsConfig selects the internal event bus. The postgres bus uses
+// LISTEN/NOTIFY on Channel so every replica sees every change; local only
+// reaches this process.
+type EventsConfig struct {
+	Bus     string `yaml:"bus" toml:"bus"`
+	Channel string `yaml:"channel" toml:"channel"`
+}
+
+// MessagesConfig limits direct messages. MaxGroupSize counts every member,
+// including the one starting the conversation.
+type MessagesConfig struct {
+	MaxGroupSize int `yaml:"max_group_size" toml:"max_group_size"`
+	MaxLength    int `yaml:"max_length" toml:"max_length"`
+}
+
+// MediaConfig controls uploads. Storage is local, keeping files under Dir,
+// or s3 for any S3-compatible service. Uploads not attached to a post
+// within UnattachedTTL are deleted every GCInterval.
+type MediaConfig struct {
+	Storage       string        `yaml:"storage" toml:"storage"`
+	Dir           string        `yaml:"dir" toml:"dir"`
+	MaxSize       int           `yaml:"max_size" toml:"max_size"`
+	MaxPerPost    int           `yaml:"max_per_post" toml:"max_per_post"`
+	UnattachedTTL time.Duration `yaml:"unattached_ttl" toml:"unattached_ttl"`
+	GCInterval    time.Duration `yaml:"gc_interval" toml:"gc_interval"`
+	S3Endpoint    string        `yaml:"s3_endpoint" toml:"s3_endpoint"`
+	S3Region      string        `yaml:"s3_region" toml:"s3_region"`
+	S3Bucket      string        `yaml:"s3_bucket" toml:"s3_bucket"`
+	S3AccessKey   string        `yaml:"s3_access_key" toml:"s3_access_key"`
+	S3SecretKey   string        `yaml:"s3_secret_key" toml:"s3_secret_key"`
+	S3PathStyle   bool          `yaml:"s3_path_style" toml:"s3_path_style"`
+}
+
+// UsersConfig controls profiles. An old handle keeps redirecting to its
+// user, and cannot be taken by anyone else, for HandleRedirectTTL after a
+// change.
+type UsersConfig struct {
+	HandleRedirectTTL time.Duration `yaml:"handle_redirect_ttl" toml:"handle_redirect_ttl"`
+}
+
+type FeaturesConfig struct {
+	Swagger bool `yaml:"swagger" toml:"swagger"`
+}
+
+var (
+	current *Config
+	mu      sync.RWMutex
+)
+
+// Default returns the configuration used before any file, environment
+// variable or flag is applied.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Port:              "8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       120 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+			DrainDelay:        5 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Host:     "localhost",
+			Port:     "5432",
+			MaxConns: 10,
+
+			ConnectTimeout:        30 * time.Second,
+			ConnectAttemptTimeout: 5 * time.Second,
+			RetryBackoff:          250 * time.Millisecond,
+			RetryMaxBackoff:       5 * time.Second,
+			QueryAttempts:         3,
+			StatementTimeout:      5 * time.Second,
+			SlowQueryThreshold:    500 * time.Millisecond,
+		},
+		Auth: AuthConfig{
+			TokenTTL: 24 * time.Hour,
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
+		},
+		Health: HealthConfig{
+			CheckTimeout: 2 * time.Second,
+			CacheTTL:     2 * time.Second,
+		},
+		Posts: PostsConfig{
+			CommentMaxDepth: 5,
+			Reactions:       []string{"like", "love", "laugh", "wow", "sad", "angry"},
+		},
+		Trending: TrendingConfig{
+			Interval:      10 * time.Minute,
+			Timeout:       2 * time.Minute,
+			HalfLife:      6 * time.Hour,
+			Windows:       []string{"1h", "24h", "168h"},
+			DefaultWindow: "24h",
+		},
+		Mail: MailConfig{
+			Transport: "file",
+			From:      "DevBook <no-reply@localhost>",
+			Dir:       "mail",
+			SMTPPort:  "587",
+		},
+		Recovery: RecoveryConfig{
+			Delay:    72 * time.Hour,
+			TokenTTL: 24 * time.Hour,
+		},
+		Stream: StreamConfig{
+			Heartbeat:             15 * time.Second,
+			ReplayBuffer:          1000,
+			MaxConnectionsPerUser: 5,
+		},
+		Socket: SocketConfig{
+			PingInterval: 30 * time.Second,
+			SendBuffer:   64,
+			MaxTopics:    100,
+		},
+		Events: EventsConfig{
+			Bus:     "postgres",
+			Channel: "api_events",
+		},
+		Messages: MessagesConfig{
+			MaxGroupSize: 10,
+			MaxLength:    2000,
+		},
+		Media: MediaConfig{
+			Storage:       "local",
+			Dir:           "media",
+			MaxSize:       10 << 20,
+			MaxPerPost:    4,
+			UnattachedTTL: 24 * time.Hour,
+			GCInterval:    time.Hour,
+		},
+		Users: UsersConfig{
+			HandleRedirectTTL: 30 * 24 * time.Hour,
+		},
+		Features: FeaturesConfig{
+			Swagger: true,
+		},
+	}
+}
+
+// Get returns the loaded configuration, or the defaults if Load has not
+// been called yet.
+func Get() *Config {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if current == nil {
+		cfg := Default()
+		return &cfg
+	}
+	return current
+}
+
+func set(cfg *Config) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = cfg
+}
+
+// Addr returns the address the HTTP server listens on. ListenAddr takes
+// precedence over Port when both are set.
+func (c ServerConfig) Addr() string {
+	if c.ListenAddr != "" {
+		return c.ListenAddr
+	}
+	return ":" + c.Port
+}
+
+// ConnString returns the Postgres connection string, preferring URL over
+// the individual fields.
+func (c DatabaseConfig) ConnString() string {
+	if c.URL != "" {
+		return c.URL
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(c.User, c.Password),
+		Host:   c.Host + ":" + c.Port,
+		Path:   "/" + c.Name,
 	}
+	return u.String()
+}
+
+// Validate reports every problem with the configuration at once.
+func (c Config) Validate() error {
+	var errs []error
+
+	if c.Server.ListenAddr == "" && c.Server.Port == "" {
+		errs = append(errs, errors.New("server.port or server.listen_addr is required"))
+	}
+
+	durations := map[string]time.Duration{
+		"server.read_header_timeout":       c.Server.ReadHeaderTimeout,
+		"server.read_timeout":              c.Server.ReadTimeout,
+		"server.write_timeout":             c.Server.WriteTimeout,
+		"server.idle_timeout":              c.Server.IdleTimeout,
+		"server.shutdown_timeout":          c.Server.ShutdownTimeout,
+		"database.connect_timeout":         c.Database.ConnectTimeout,
+		"database.connect_attempt_timeout": c.Database.ConnectAttemptTimeout,
+		"database.retry_backoff":           c.Database.RetryBackoff,
+		"database.retry_max_backoff":       c.Database.RetryMaxBackoff,
+		"database.statement_timeout":       c.Database.StatementTimeout,
+		"auth.token_ttl":                   c.Auth.TokenTTL,
+		"health.check_timeout":             c.Health.CheckTimeout,
+		"trending.timeout":                 c.Trending.Timeout,
+		"trending.half_life":               c.Trending.HalfLife,
+		"recovery.delay":                   c.Recovery.Delay,
+		"recovery.token_ttl":               c.Recovery.TokenTTL,
+		"stream.heartbeat":                 c.Stream.Heartbeat,
+		"socket.ping_interval":             c.Socket.PingInterval,
+		"media.unattached_ttl":             c.Media.UnattachedTTL,
+		"users.handle_redirect_ttl":        c.Users.HandleRedirectTTL,
+	}
+	for _, name := range sortedKeys(durations) {
+		if durations[name] <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", name))
+		}
+	}
+	if c.Health.CacheTTL < 0 {
+		errs = append(errs, errors.New("health.cache_ttl must not be negative"))
+	}
+	if c.Server.DrainDelay < 0 {
+		errs = append(errs, errors.New("server.drain_delay must not be negative"))
+	}
+
+	if c.Database.URL == "" {
+		if c.Database.Host == "" {
+			errs = append(errs, errors.New("database.host is required"))
+		}
+		if c.Database.Name == "" {
+			errs = append(errs, errors.New("database.name is required"))
+		}
+		if c.Database.User == "" {
+			errs = append(errs, errors.New("database.user is required"))
+		}
+	} else if _, err := url.Parse(c.Database.URL); err != nil {
+		errs = append(errs, errors.New("database.url is not a valid URL"))
+	}
+	if c.Database.MaxConns <= 0 {
+		errs = append(errs, errors.New("database.max_conns must be positive"))
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		errs = append(errs, errors.New("database.slow_query_threshold must not be negative"))
+	}
+	if c.Database.QueryAttempts <= 0 {
+		errs = append(errs, errors.New("database.query_attempts must be positive"))
+	}
+
+	if c.Posts.CommentMaxDepth < 0 {
+		errs = append(errs, errors.New("posts.comment_max_depth must not be negative"))
+	}
+
+	if len(c.Posts.Reactions) == 0 {
+		errs = append(errs, errors.New("posts.reactions must list at least one reaction"))
+	}
+	for _, reaction := range c.Posts.Reactions {
+		if !reactionPattern.MatchString(reaction) {
+			errs = append(errs, fmt.Errorf("posts.reactions: %q must be 1-32 lowercase letters, digits or underscores", reaction))
+		}
+	}
+
+	if c.Trending.Interval < 0 {
+		errs = append(errs, errors.New("trending.interval must not be negative"))
+	}
+	if len(c.Trending.Windows) == 0 {
+		errs = append(errs, errors.New("trending.windows must list at least one window"))
+	}
+	for _, window := range c.Trending.Windows {
+		if d, err := time.ParseDuration(window); err != nil || d <= 0 {
+			errs = append(errs, fmt.Errorf("trending.windows: %q must be a positive duration", window))
+		}
+	}
+	if !slices.Contains(c.Trending.Windows, c.Trending.DefaultWindow) {
+		errs = append(errs, fmt.Errorf("trending.default_window %q must be one of trending.windows", c.Trending.DefaultWindow))
+	}
+
+	if c.Stream.ReplayBuffer < 0 {
+		errs = append(errs, errors.New("stream.replay_buffer must not be negative"))
+	}
+	if c.Stream.MaxConnectionsPerUser <= 0 {
+		errs = append(errs, errors.New("stream.max_connections_per_user must be positive"))
+	}
+
+	if c.Socket.SendBuffer <= 0 {
+		errs = append(errs, errors.New("socket.send_buffer must be positive"))
+	}
+	if c.Socket.MaxTopics <= 0 {
+		errs = append(errs, errors.New("socket.max_topics must be positive"))
+	}
+
+	switch c.Events.Bus {
+	case "local":
+	case "postgres":
+		if !channelPattern.MatchString(c.Events.Channel) {
+			errs = append(errs, fmt.Errorf("events.channel %q must be a lowercase identifier of at most 63 characters", c.Events.Channel))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("events.bus %q must be postgres or local", c.Events.Bus))
+	}
+
+	if c.Messages.MaxGroupSize < 2 {
+		errs = append(errs, errors.New("messages.max_group_size must be at least 2"))
+	}
+	if c.Messages.MaxLength <= 0 {
+		errs = append(errs, errors.New("messages.max_length must be positive"))
+	}
+
+	if c.Media.MaxSize <= 0 {
+		errs = append(errs, errors.New("media.max_size must be positive"))
+	}
+	if c.Media.MaxPerPost < 0 {
+		errs = append(errs, errors.New("media.max_per_post must not be negative"))
+	}
+	if c.Media.GCInterval < 0 {
+		errs = append(errs, errors.New("media.gc_interval must not be negative"))
+	}
+	switch c.Media.Storage {
+	case "local":
+		if c.Media.Dir == "" {
+			errs = append(errs, errors.New("media.dir is required for local storage"))
+		}
+	case "s3":
+		if c.Media.S3Endpoint == "" {
+			errs = append(errs, errors.New("media.s3_endpoint is required for s3 storage"))
+		} else if u, err := url.Parse(c.Media.S3Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("media.s3_endpoint %q must be an http or https URL", c.Media.S3Endpoint))
+		}
+		if c.Media.S3Bucket == "" {
+			errs = append(errs, errors.New("media.s3_bucket is required for s3 storage"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("media.storage %q must be local or s3", c.Media.Storage))
+	}
+
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		errs = append(errs, fmt.Errorf("mail.from %q is not a valid address", c.Mail.From))
+	}
+	switch c.Mail.Transport {
+	case "file":
+		if c.Mail.Dir == "" {
+			errs = append(errs, errors.New("mail.dir is required for the file transport"))
+		}
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			errs = append(errs, errors.New("mail.smtp_host is required for the smtp transport"))
+		}
+		if c.Mail.SMTPPort == "" {
+			errs = append(errs, errors.New("mail.smtp_port is required for the smtp transport"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("mail.transport %q must be smtp or file", c.Mail.Transport))
+	}
+
+	if c.Auth.Secret == "" {
+		errs = append(errs, errors.New("auth.secret is required"))
+	}
+
+	switch c.Log.Level {
+	case "trace", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level %q must be one of trace, debug, info, warn, error", c.Log.Level))
+	}
+
+	switch c.Log.Format {
+	case "json", "console":
+	default:
+		errs = append(errs, fmt.Errorf("log.format %q must be json or console", c.Log.Format))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration with secrets masked.
+func (c Config) Redacted() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = redacted
+	}
+	if c.Database.URL != "" {
+		if u, err := url.Parse(c.Database.URL); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redacted)
+				c.Database.URL = strings.Replace(u.String(), url.QueryEscape(redacted), redacted, 1)
+			}
+		}
+	}
+	if c.Auth.Secret != "" {
+		c.Auth.Secret = redacted
+	}
+	if c.Mail.SMTPPassword != "" {
+		c.Mail.SMTPPassword = redacted
+	}
+	if c.Media.S3SecretKey != "" {
+		c.Media.S3SecretKey = redacted
+	}
+	return c
+}
+
+// Print writes the effective configuration as YAML with secrets redacted.
+func Print(w io.Writer, c Config) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	defer encoder.Close()
 
-	DatabasePort = os.Getenv("DATABASE_PORT")
-	DatabaseHost = os.Getenv("DATABASE_HOST")
-	DatabaseName = os.Getenv("DATABASE_NAME")
-	DatabaseUser = os.Getenv("DATABASE_USER")
-	DatabasePassword = os.Getenv("DATABASE_PASSWORD")
-	ApiSecret = os.Getenv("API_SECRET")
+	return encoder.Encode(c.Redacted())
 }
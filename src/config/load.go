@@ -0,0 +1,300 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// binding ties a config field to its environment variable and CLI flag.
+// Secret bindings may also be read from a file named by <ENV>_FILE.
+type binding struct {
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	target any
+}
+
+func bindings(c *Config) []binding {
+	return []binding{
+		{env: "PORT", flag: "port", usage: "port to listen on", target: &c.Server.Port},
+		{env: "LISTEN_ADDR", flag: "listen-addr", usage: "address to listen on, overrides port", target: &c.Server.ListenAddr},
+		{env: "SERVER_READ_HEADER_TIMEOUT", flag: "read-header-timeout", usage: "time allowed to read request headers", target: &c.Server.ReadHeaderTimeout},
+		{env: "SERVER_READ_TIMEOUT", flag: "read-timeout", usage: "time allowed to read a request", target: &c.Server.ReadTimeout},
+		{env: "SERVER_WRITE_TIMEOUT", flag: "write-timeout", usage: "time allowed to write a response", target: &c.Server.WriteTimeout},
+		{env: "SERVER_IDLE_TIMEOUT", flag: "idle-timeout", usage: "keep-alive idle timeout", target: &c.Server.IdleTimeout},
+		{env: "SERVER_SHUTDOWN_TIMEOUT", flag: "shutdown-timeout", usage: "time allowed for in-flight requests on shutdown", target: &c.Server.ShutdownTimeout},
+		{env: "SERVER_DRAIN_DELAY", flag: "drain-delay", usage: "time readiness fails before shutdown starts", target: &c.Server.DrainDelay},
+		{env: "DATABASE_URL", flag: "database-url", usage: "Postgres connection URL", secret: true, target: &c.Database.URL},
+		{env: "DATABASE_HOST", flag: "database-host", usage: "Postgres host", target: &c.Database.Host},
+		{env: "DATABASE_PORT", flag: "database-port", usage: "Postgres port", target: &c.Database.Port},
+		{env: "DATABASE_NAME", flag: "database-name", usage: "Postgres database name", target: &c.Database.Name},
+		{env: "DATABASE_USER", flag: "database-user", usage: "Postgres user", target: &c.Database.User},
+		{env: "DATABASE_PASSWORD", flag: "database-password", usage: "Postgres password", secret: true, target: &c.Database.Password},
+		{env: "DATABASE_MAX_CONNS", flag: "database-max-conns", usage: "maximum pooled connections", target: &c.Database.MaxConns},
+		{env: "DATABASE_CONNECT_TIMEOUT", flag: "database-connect-timeout", usage: "how long startup keeps retrying the database", target: &c.Database.ConnectTimeout},
+		{env: "DATABASE_CONNECT_ATTEMPT_TIMEOUT", flag: "database-connect-attempt-timeout", usage: "time allowed for a single connection attempt", target: &c.Database.ConnectAttemptTimeout},
+		{env: "DATABASE_RETRY_BACKOFF", flag: "database-retry-backoff", usage: "initial delay between database retries", target: &c.Database.RetryBackoff},
+		{env: "DATABASE_RETRY_MAX_BACKOFF", flag: "database-retry-max-backoff", usage: "maximum delay between database retries", target: &c.Database.RetryMaxBackoff},
+		{env: "DATABASE_QUERY_ATTEMPTS", flag: "database-query-attempts", usage: "attempts for idempotent queries failing with transient errors", target: &c.Database.QueryAttempts},
+		{env: "DATABASE_STATEMENT_TIMEOUT", flag: "database-statement-timeout", usage: "default time limit for a repository call", target: &c.Database.StatementTimeout},
+		{env: "DATABASE_SLOW_QUERY_THRESHOLD", flag: "database-slow-query-threshold", usage: "statements slower than this are logged", target: &c.Database.SlowQueryThreshold},
+		{env: "API_SECRET", flag: "api-secret", usage: "secret used to sign JWTs", secret: true, target: &c.Auth.Secret},
+		{env: "AUTH_TOKEN_TTL", flag: "token-ttl", usage: "lifetime of issued JWTs", target: &c.Auth.TokenTTL},
+		{env: "ADMIN_USER_IDS", flag: "admin-user-ids", usage: "comma-separated IDs of admin users", target: &c.Auth.AdminIDs},
+		{env: "LOG_LEVEL", flag: "log-level", usage: "trace, debug, info, warn or error", target: &c.Log.Level},
+		{env: "LOG_FORMAT", flag: "log-format", usage: "json or console", target: &c.Log.Format},
+		{env: "HEALTH_CHECK_TIMEOUT", flag: "health-check-timeout", usage: "time each readiness check may run", target: &c.Health.CheckTimeout},
+		{env: "HEALTH_CACHE_TTL", flag: "health-cache-ttl", usage: "how long readiness check results are reused", target: &c.Health.CacheTTL},
+		{env: "COMMENT_MAX_DEPTH", flag: "comment-max-depth", usage: "deepest reply level allowed, 0 disables replies", target: &c.Posts.CommentMaxDepth},
+		{env: "REACTION_TYPES", flag: "reaction-types", usage: "comma-separated reactions users can add to posts", target: &c.Posts.Reactions},
+		{env: "TRENDING_INTERVAL", flag: "trending-interval", usage: "how often trending tags are recomputed, 0 disables the job", target: &c.Trending.Interval},
+		{env: "TRENDING_TIMEOUT", flag: "trending-timeout", usage: "time each window's recomputation may run", target: &c.Trending.Timeout},
+		{env: "TRENDING_HALF_LIFE", flag: "trending-half-life", usage: "age at which a post counts half towards trending tags", target: &c.Trending.HalfLife},
+		{env: "TRENDING_WINDOWS", flag: "trending-windows", usage: "comma-separated windows trending tags are computed for", target: &c.Trending.Windows},
+		{env: "TRENDING_DEFAULT_WINDOW", flag: "trending-default-window", usage: "window served when none is requested", target: &c.Trending.DefaultWindow},
+		{env: "MAIL_TRANSPORT", flag: "mail-transport", usage: "smtp, or file to write emails to mail-dir", target: &c.Mail.Transport},
+		{env: "MAIL_FROM", flag: "mail-from", usage: "sender address of outgoing emails", target: &c.Mail.From},
+		{env: "MAIL_DIR", flag: "mail-dir", usage: "directory the file transport writes emails to", target: &c.Mail.Dir},
+		{env: "SMTP_HOST", flag: "smtp-host", usage: "SMTP server host", target: &c.Mail.SMTPHost},
+		{env: "SMTP_PORT", flag: "smtp-port", usage: "SMTP server port", target: &c.Mail.SMTPPort},
+		{env: "SMTP_USER", flag: "smtp-user", usage: "SMTP username, empty to skip authentication", target: &c.Mail.SMTPUser},
+		{env: "SMTP_PASSWORD", flag: "smtp-password", usage: "SMTP password", secret: true, target: &c.Mail.SMTPPassword},
+		{env: "RECOVERY_DELAY", flag: "recovery-delay", usage: "cooling-off time before an account recovery can be confirmed", target: &c.Recovery.Delay},
+		{env: "RECOVERY_TOKEN_TTL", flag: "recovery-token-ttl", usage: "lifetime of recovery and email verification tokens", target: &c.Recovery.TokenTTL},
+		{env: "STREAM_HEARTBEAT", flag: "stream-heartbeat", usage: "interval between keep-alive messages on live streams", target: &c.Stream.Heartbeat},
+		{env: "STREAM_REPLAY_BUFFER", flag: "stream-replay-buffer", usage: "recent events kept for clients resuming a stream", target: &c.Stream.ReplayBuffer},
+		{env: "STREAM_MAX_CONNECTIONS_PER_USER", flag: "stream-max-connections-per-user", usage: "live streams a user may have open at once", target: &c.Stream.MaxConnectionsPerUser},
+		{env: "SOCKET_PING_INTERVAL", flag: "socket-ping-interval", usage: "interval between WebSocket pings", target: &c.Socket.PingInterval},
+		{env: "SOCKET_SEND_BUFFER", flag: "socket-send-buffer", usage: "messages queued per WebSocket before the client is evicted", target: &c.Socket.SendBuffer},
+		{env: "SOCKET_MAX_TOPICS", flag: "socket-max-topics", usage: "topics a WebSocket connection may subscribe to", target: &c.Socket.MaxTopics},
+		{env: "EVENTS_BUS", flag: "events-bus", usage: "postgres to share events between replicas, or local", target: &c.Events.Bus},
+		{env: "EVENTS_CHANNEL", flag: "events-channel", usage: "Postgres channel events are published on", target: &c.Events.Channel},
+		{env: "MESSAGES_MAX_GROUP_SIZE", flag: "messages-max-group-size", usage: "members allowed in a conversation, including its creator", target: &c.Messages.MaxGroupSize},
+		{env: "MESSAGES_MAX_LENGTH", flag: "messages-max-length", usage: "characters allowed in a direct message", target: &c.Messages.MaxLength},
+		{env: "MEDIA_STORAGE", flag: "media-storage", usage: "local, or s3 for an S3-compatible service", target: &c.Media.Storage},
+		{env: "MEDIA_DIR", flag: "media-dir", usage: "directory local storage keeps uploads in", target: &c.Media.Dir},
+		{env: "MEDIA_MAX_SIZE", flag: "media-max-size", usage: "largest upload accepted, in bytes", target: &c.Media.MaxSize},
+		{env: "MEDIA_MAX_PER_POST", flag: "media-max-per-post", usage: "uploads a post may attach", target: &c.Media.MaxPerPost},
+		{env: "MEDIA_UNATTACHED_TTL", flag: "media-unattached-ttl", usage: "time an upload may stay unattached before it is deleted", target: &c.Media.UnattachedTTL},
+		{env: "MEDIA_GC_INTERVAL", flag: "media-gc-interval", usage: "interval between deletions of unattached uploads, 0 to disable", target: &c.Media.GCInterval},
+		{env: "S3_ENDPOINT", flag: "s3-endpoint", usage: "URL of the S3-compatible service", target: &c.Media.S3Endpoint},
+		{env: "S3_REGION", flag: "s3-region", usage: "S3 region", target: &c.Media.S3Region},
+		{env: "S3_BUCKET", flag: "s3-bucket", usage: "S3 bucket uploads are stored in", target: &c.Media.S3Bucket},
+		{env: "S3_ACCESS_KEY", flag: "s3-access-key", usage: "S3 access key ID", target: &c.Media.S3AccessKey},
+		{env: "S3_SECRET_KEY", flag: "s3-secret-key", usage: "S3 secret access key", secret: true, target: &c.Media.S3SecretKey},
+		{env: "S3_PATH_STYLE", flag: "s3-path-style", usage: "address buckets by path, as MinIO and most stand-ins expect", target: &c.Media.S3PathStyle},
+		{env: "USERS_HANDLE_REDIRECT_TTL", flag: "users-handle-redirect-ttl", usage: "time an old handle keeps redirecting after a change", target: &c.Users.HandleRedirectTTL},
+		{env: "FEATURE_SWAGGER", flag: "feature-swagger", usage: "serve the Swagger UI", target: &c.Features.Swagger},
+	}
+}
+
+// Load builds the configuration from defaults, then an optional YAML or
+// TOML file, then environment variables, then CLI flags. The result is
+// validated and, if valid, becomes the value returned by Get. All
+// problems found along the way are reported together.
+func Load(args []string) (*Config, error) {
+	cfg, err := resolve(args)
+	if err != nil {
+		return cfg, err
+	}
+
+	set(cfg)
+	return cfg, nil
+}
+
+func resolve(args []string) (*Config, error) {
+	cfg := Default()
+	var errs []error
+
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		errs = append(errs, fmt.Errorf(".env: %w", err))
+	}
+
+	flags, configFile, err := parseFlags(&cfg, args)
+	if err != nil {
+		return &cfg, err
+	}
+
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+	if configFile != "" {
+		if err := loadFile(&cfg, configFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, b := range bindings(&cfg) {
+		value, ok, err := lookupEnv(b)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := assign(b.target, value); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", b.env, err))
+		}
+	}
+
+	for _, b := range bindings(&cfg) {
+		value, ok := flags[b.flag]
+		if !ok {
+			continue
+		}
+		if err := assign(b.target, value); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", b.flag, err))
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return &cfg, errors.Join(errs...)
+}
+
+// parseFlags records which flags were given without applying them, so they
+// can be layered on top of the file and environment afterwards.
+func parseFlags(cfg *Config, args []string) (map[string]string, string, error) {
+	flagSet := flag.NewFlagSet("api", flag.ContinueOnError)
+	values := map[string]string{}
+
+	var configFile string
+	flagSet.StringVar(&configFile, "config", "", "path to a YAML or TOML config file")
+
+	for _, b := range bindings(cfg) {
+		_, isBool := b.target.(*bool)
+		flagSet.Var(&recorder{name: b.flag, values: values, isBool: isBool}, b.flag, b.usage)
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if flagSet.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected arguments: %s", strings.Join(flagSet.Args(), " "))
+	}
+
+	return values, configFile, nil
+}
+
+type recorder struct {
+	name   string
+	values map[string]string
+	isBool bool
+}
+
+func (r *recorder) String() string   { return "" }
+func (r *recorder) IsBoolFlag() bool { return r.isBool }
+
+func (r *recorder) Set(value string) error {
+	r.values[r.name] = value
+	return nil
+}
+
+func loadFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("config file %s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("config file %s: unsupported extension, use .yaml, .yml or .toml", path)
+	}
+
+	return nil
+}
+
+// lookupEnv reads a binding's environment variable. For secrets, a
+// <ENV>_FILE variable naming a file takes precedence, so values can be
+// mounted by the orchestrator instead of exposed in the environment.
+func lookupEnv(b binding) (string, bool, error) {
+	if b.secret {
+		if path := os.Getenv(b.env + "_FILE"); path != "" {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return "", false, fmt.Errorf("%s_FILE: %w", b.env, err)
+			}
+			return strings.TrimSpace(string(data)), true, nil
+		}
+	}
+
+	value, ok := os.LookupEnv(b.env)
+	return value, ok && value != "", nil
+}
+
+func assign(target any, value string) error {
+	switch t := target.(type) {
+	case *string:
+		*t = value
+	case *int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		*t = n
+	case *bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		*t = b
+	case *time.Duration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		*t = d
+	case *[]string:
+		var items []string
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		*t = items
+	default:
+		return fmt.Errorf("unsupported config type %T", target)
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/controllers/dto"
+	"api/src/database"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+const maxOnThisDay = 100
+
+// Archive godoc
+// @Summary Get a user's post archive
+// @Description Count a user's posts per year and per month, newest first. Months are calendar months in UTC and months without posts are left out
+// @Tags Archive
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} models.Archive "Post counts"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/archive [get]
+func ArchiveGet(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(userID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	months, err := repositories.NewPostsRepository(db).CountByMonth(r.Context(), userID)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to count posts")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch archive"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, groupArchive(userID, months))
+}
+
+// groupArchive folds per-month counts, newest first, into years.
+func groupArchive(userID string, months []models.ArchiveMonth) models.Archive {
+	archive := models.Archive{UserID: userID, Years: []models.ArchiveYear{}}
+
+	for _, month := range months {
+		last := len(archive.Years) - 1
+		if last < 0 || archive.Years[last].Year != month.Year {
+			archive.Years = append(archive.Years, models.ArchiveYear{Year: month.Year, Months: []models.ArchiveMonth{}})
+			last++
+		}
+
+		archive.Years[last].Count += month.Count
+		archive.Years[last].Months = append(archive.Years[last].Months, month)
+		archive.Total += month.Count
+	}
+
+	return archive
+}
+
+// Archive godoc
+// @Summary Get a user's posts from one month
+// @Description Get the posts a user made in a calendar month (UTC), newest first, with cursor pagination
+// @Tags Archive
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param year path int true "Year"
+// @Param month path int true "Month, 1 to 12"
+// @Param limit query int false "Number of posts to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Posts] "Page of posts"
+// @Failure 400 {object} map[string]string "Invalid month or cursor"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/archive/{year}/{month} [get]
+func ArchiveGetMonth(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
+	if _, err := uuid.Parse(userID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	year, err := strconv.Atoi(params["year"])
+	if err != nil || year < 1 || year > 9999 {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid year"})
+		return
+	}
+
+	month, err := strconv.Atoi(params["month"])
+	if err != nil || month < 1 || month > 12 {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid month"})
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	filters := repositories.TimelineFilters{AuthorID: userID, Month: &start, After: after}
+
+	repository := repositories.NewPostsRepository(db)
+	posts, err := repository.FindTimeline(r.Context(), filters, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch archived posts")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage[models.Posts](posts, limit, postPosition))
+}
+
+// Archive godoc
+// @Summary Get your posts from this day in previous years
+// @Description Get the authenticated user's posts made on today's month and day in earlier years, newest first, at most 100. Dates follow the user's time zone, or UTC if the database does not know it
+// @Tags Archive
+// @Accept json
+// @Produce json
+// @Success 200 {array} models.Posts "Posts"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/on-this-day [get]
+// @Security ApiKeyAuth
+func OnThisDay(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	timeZone, err := repositories.NewUsersRepository(db).FindTimeZone(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if timeZone == "" {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	repository := repositories.NewPostsRepository(db)
+	posts, err := repository.FindOnThisDay(r.Context(), userID, timeZone, maxOnThisDay)
+	if errors.Is(err, repositories.ErrUnknownTimeZone) {
+		// TimeZoneSet checks zones against Go's database, which Postgres
+		// may not share. UTC is the default zone anyway.
+		log.Warn().Str("time_zone", timeZone).Msg("Time zone unknown to the database, using UTC")
+		posts, err = repository.FindOnThisDay(r.Context(), userID, "UTC", maxOnThisDay)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("time_zone", timeZone).Msg("Failed to fetch posts from this day")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, posts)
+}
+
+// Archive godoc
+// @Summary Get your time zone
+// @Description Get the IANA time zone used for the authenticated user's on-this-day posts. Defaults to UTC
+// @Tags Archive
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.TimeZoneDTO "Time zone"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/time-zone [get]
+// @Security ApiKeyAuth
+func TimeZoneGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	timeZone, err := repositories.NewUsersRepository(db).FindTimeZone(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if timeZone == "" {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, dto.TimeZoneDTO{TimeZone: timeZone})
+}
+
+// Archive godoc
+// @Summary Set your time zone
+// @Description Set the IANA time zone, such as Europe/Lisbon, used for the authenticated user's on-this-day posts
+// @Tags Archive
+// @Accept json
+// @Produce json
+// @Param request body dto.TimeZoneDTO true "Time zone"
+// @Success 200 {object} dto.TimeZoneDTO "Time zone"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/time-zone [put]
+// @Security ApiKeyAuth
+func TimeZoneSet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	var timeZoneDTO dto.TimeZoneDTO
+	if !decodeBody(w, r, &timeZoneDTO) {
+		return
+	}
+
+	// "Local" would mean the server's zone, which Postgres doesn't know.
+	if _, err := time.LoadLocation(timeZoneDTO.TimeZone); err != nil || timeZoneDTO.TimeZone == "Local" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Unknown time zone"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	if err := repositories.NewUsersRepository(db).SetTimeZone(r.Context(), userID, timeZoneDTO.TimeZone); err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update time zone"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, timeZoneDTO)
+}
@@ -4,12 +4,15 @@ import (
 	"api/src/auth"
 	"api/src/database"
 	"api/src/models"
+	"api/src/notify"
 	"api/src/repositories"
 	"api/src/responses"
 	"encoding/json"
 	"io"
+	"net"
 	"net/http"
 
+	"github.com/rs/zerolog/log"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -66,11 +69,10 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
 		return
 	}
-	defer db.Close(r.Context())
 
 	repository := repositories.NewUsersRepository(db)
 
-	user, err := repository.FindByEmail(authRequest.Email)
+	user, err := repository.FindByEmail(r.Context(), authRequest.Email)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
@@ -94,9 +96,26 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	_, err = notify.Publish(r.Context(), user.ID.String(), notify.KindLogin, map[string]string{
+		"ip":         clientIP(r),
+		"user_agent": r.UserAgent(),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to publish login notification")
+	}
+
 	responses.JsonResponse(w, http.StatusOK, map[string]string{"token": token})
 }
 
+// clientIP returns the address the request came from, without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // SignIn godoc
 // @Summary Register a new user
 // @Description Creates a new user and returns a JWT token
@@ -139,11 +158,10 @@ func SignIn(w http.ResponseWriter, r *http.Request) {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
 		return
 	}
-	defer db.Close(r.Context())
 
 	repository := repositories.NewUsersRepository(db)
 
-	userExists, err := repository.FindByEmail(signInRequest.Email)
+	userExists, err := repository.FindByEmail(r.Context(), signInRequest.Email)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
 		return
@@ -154,7 +172,7 @@ func SignIn(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userID, err := repository.Create(models.User{
+	userID, err := repository.Create(r.Context(), models.User{
 		Name:     signInRequest.Name,
 		Email:    signInRequest.Email,
 		Password: string(passwordHash),
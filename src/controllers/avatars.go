@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/database"
+	"api/src/identicon"
+	"api/src/media"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/storage"
+	"bytes"
+	"errors"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// profileImage describes one kind of profile image. Stored objects are
+// keyed "<dir>/<uuid><ext>", which is also their path in the API.
+type profileImage struct {
+	column string
+	dir    string
+	width  int
+	height int
+}
+
+var (
+	avatarImage = profileImage{column: repositories.ImageAvatar, dir: "avatars", width: media.AvatarSize, height: media.AvatarSize}
+	bannerImage = profileImage{column: repositories.ImageBanner, dir: "banners", width: media.BannerWidth, height: media.BannerHeight}
+)
+
+// Users godoc
+// @Summary Upload your avatar
+// @Description Upload a JPEG, PNG, GIF or WebP image as the authenticated user's avatar. The type is detected from the content. The image is center-cropped to a square and scaled to 400x400, which also drops its metadata
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Image"
+// @Success 200 {object} models.User "Updated user"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "Unsupported media type"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/avatar [put]
+// @Security ApiKeyAuth
+func AvatarUpload(w http.ResponseWriter, r *http.Request) {
+	uploadProfileImage(w, r, avatarImage)
+}
+
+// Users godoc
+// @Summary Remove your avatar
+// @Description Remove the authenticated user's avatar. Their generated identicon is shown instead
+// @Tags Users
+// @Produce json
+// @Success 200 {object} models.User "Updated user"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/avatar [delete]
+// @Security ApiKeyAuth
+func AvatarDelete(w http.ResponseWriter, r *http.Request) {
+	deleteProfileImage(w, r, avatarImage)
+}
+
+// Users godoc
+// @Summary Upload your profile banner
+// @Description Upload a JPEG, PNG, GIF or WebP image as the authenticated user's profile banner. The type is detected from the content. The image is center-cropped to 3:1 and scaled to 1500x500, which also drops its metadata
+// @Tags Users
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Image"
+// @Success 200 {object} models.User "Updated user"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "Unsupported media type"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/banner [put]
+// @Security ApiKeyAuth
+func BannerUpload(w http.ResponseWriter, r *http.Request) {
+	uploadProfileImage(w, r, bannerImage)
+}
+
+// Users godoc
+// @Summary Remove your profile banner
+// @Description Remove the authenticated user's profile banner
+// @Tags Users
+// @Produce json
+// @Success 200 {object} models.User "Updated user"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/banner [delete]
+// @Security ApiKeyAuth
+func BannerDelete(w http.ResponseWriter, r *http.Request) {
+	deleteProfileImage(w, r, bannerImage)
+}
+
+func uploadProfileImage(w http.ResponseWriter, r *http.Request, kind profileImage) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	image, ok := readImage(w, r)
+	if !ok {
+		return
+	}
+
+	cropped, err := media.Crop(image, kind.width, kind.height)
+	if errors.Is(err, media.ErrTooManyPixels) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Image dimensions are too large"})
+		return
+	}
+	if errors.Is(err, media.ErrMalformed) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Malformed image"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to crop image")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to process image"})
+		return
+	}
+
+	store, err := storage.Get()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Media storage is not available"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	key := kind.dir + "/" + uuid.NewString() + cropped.Extension
+	if err := store.Put(r.Context(), key, bytes.NewReader(cropped.Data), int64(len(cropped.Data)), cropped.MimeType); err != nil {
+		log.Error().Err(err).Msg("Failed to store image")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to store image"})
+		return
+	}
+
+	previous, found, err := repositories.NewUsersRepository(db).SetImage(r.Context(), userID, kind.column, &key)
+	if err != nil || !found {
+		if err := store.Delete(r.Context(), key); err != nil {
+			log.Error().Err(err).Str("key", key).Msg("Failed to delete unrecorded image")
+		}
+	}
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save image"})
+		return
+	}
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	deleteStoredImage(r, previous)
+	respondWithUser(w, r, db, userID)
+}
+
+func deleteProfileImage(w http.ResponseWriter, r *http.Request, kind profileImage) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	previous, found, err := repositories.NewUsersRepository(db).SetImage(r.Context(), userID, kind.column, nil)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to remove image"})
+		return
+	}
+
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	deleteStoredImage(r, previous)
+	respondWithUser(w, r, db, userID)
+}
+
+// respondWithUser writes the current state of a user after a change.
+func respondWithUser(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, userID string) {
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, user)
+}
+
+// deleteStoredImage removes a replaced profile image. Failures only leave
+// an unreferenced object behind, so they are logged.
+func deleteStoredImage(r *http.Request, key *string) {
+	if key == nil {
+		return
+	}
+
+	store, err := storage.Get()
+	if err == nil {
+		err = store.Delete(r.Context(), *key)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", *key).Msg("Failed to delete replaced image")
+	}
+}
+
+// Users godoc
+// @Summary Get an avatar
+// @Description Get an uploaded avatar. Use the avatar_url of a user rather than building this path
+// @Tags Users
+// @Produce image/jpeg,image/png
+// @Param name path string true "File name"
+// @Success 200 {file} file "Image"
+// @Failure 404 {object} map[string]string "Image not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /avatars/{name} [get]
+func AvatarGet(w http.ResponseWriter, r *http.Request) {
+	sendProfileImage(w, r, avatarImage)
+}
+
+// Users godoc
+// @Summary Get a profile banner
+// @Description Get an uploaded profile banner. Use the banner_url of a user rather than building this path
+// @Tags Users
+// @Produce image/jpeg,image/png
+// @Param name path string true "File name"
+// @Success 200 {file} file "Image"
+// @Failure 404 {object} map[string]string "Image not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /banners/{name} [get]
+func BannerGet(w http.ResponseWriter, r *http.Request) {
+	sendProfileImage(w, r, bannerImage)
+}
+
+// profileImageTypes are the types media.Crop produces, by extension.
+var profileImageTypes = map[string]string{
+	".jpg": "image/jpeg",
+	".png": "image/png",
+}
+
+func sendProfileImage(w http.ResponseWriter, r *http.Request, kind profileImage) {
+	name := mux.Vars(r)["name"]
+	ext := path.Ext(name)
+
+	contentType, ok := profileImageTypes[ext]
+	if _, err := uuid.Parse(strings.TrimSuffix(name, ext)); err != nil || !ok {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Image not found"})
+		return
+	}
+
+	sendObject(w, r, kind.dir+"/"+name, contentType, -1)
+}
+
+// Users godoc
+// @Summary Get a user's identicon
+// @Description Get the generated avatar of a user, a symmetric pattern derived from their ID. It is the avatar_url of users who have not uploaded an avatar
+// @Tags Users
+// @Produce image/png
+// @Param id path string true "User ID"
+// @Success 200 {file} file "Image"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/identicon [get]
+func IdenticonGet(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	// The picture only depends on the ID, so there is no need to look the
+	// user up and it never changes.
+	data, err := identicon.PNG(id.String())
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to draw identicon")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to draw identicon"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// Bookmarks godoc
+// @Summary Bookmark a post
+// @Description Save a post for later. Bookmarks are private. Saving a post again has no effect
+// @Tags Bookmarks
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/bookmark [put]
+// @Security ApiKeyAuth
+func BookmarkAdd(w http.ResponseWriter, r *http.Request) {
+	setBookmark(w, r, true)
+}
+
+// Bookmarks godoc
+// @Summary Remove a bookmark
+// @Description Unsave a post. Removing a bookmark you do not have has no effect
+// @Tags Bookmarks
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/bookmark [delete]
+// @Security ApiKeyAuth
+func BookmarkRemove(w http.ResponseWriter, r *http.Request) {
+	setBookmark(w, r, false)
+}
+
+// Bookmarks godoc
+// @Summary List your bookmarks
+// @Description List the posts you saved, most recently saved first, with cursor pagination
+// @Tags Bookmarks
+// @Accept json
+// @Produce json
+// @Param limit query int false "Number of posts to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Posts] "Page of bookmarked posts"
+// @Failure 400 {object} map[string]string "Invalid cursor"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/bookmarks [get]
+// @Security ApiKeyAuth
+func BookmarkGetAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewBookmarksRepository(db)
+	posts, err := repository.FindManyByUserId(r.Context(), userID, after, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch bookmarks")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bookmarks"})
+		return
+	}
+
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch bookmarks"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage(posts, limit, bookmarkPosition))
+}
+
+func bookmarkPosition(post models.Posts) cursor.Position {
+	return cursor.Position{CreatedAt: *post.BookmarkedAt, ID: post.ID.String()}
+}
+
+func setBookmark(w http.ResponseWriter, r *http.Request, add bool) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+	postID := params["id"]
+
+	if _, err := uuid.Parse(postID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewBookmarksRepository(db)
+	if add {
+		if !postExists(w, r, db, postID) {
+			return
+		}
+		err = repository.Add(r.Context(), userID, postID)
+	} else {
+		err = repository.Remove(r.Context(), userID, postID)
+	}
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update bookmark"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,394 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/controllers/dto"
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// Comments godoc
+// @Summary Comment on a post
+// @Description Create a comment on a post, optionally as a reply to another comment
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param request body dto.CommentCreateDTO true "Comment data"
+// @Success 201 {object} models.Comment "Created comment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Post or parent comment not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/comments [post]
+// @Security ApiKeyAuth
+func CommentCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+	postID := params["id"]
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
+		return
+	}
+
+	var commentDTO dto.CommentCreateDTO
+	if err = json.Unmarshal(body, &commentDTO); err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Failed to unmarshal JSON"})
+		return
+	}
+
+	err = Validate.Struct(commentDTO)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Validation failed: %v", err)})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	if !postExists(w, r, db, postID) {
+		return
+	}
+	// Compare in the form the database returns.
+	postID = uuid.MustParse(postID).String()
+
+	repository := repositories.NewCommentsRepository(db)
+
+	comment := models.Comment{
+		PostID:   postID,
+		UserID:   userID,
+		ParentID: commentDTO.ParentID,
+		Content:  commentDTO.Content,
+	}
+
+	if commentDTO.ParentID != nil {
+		parent, err := repository.FindById(r.Context(), *commentDTO.ParentID)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find parent comment"})
+			return
+		}
+
+		if parent == nil || parent.PostID != postID {
+			responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Parent comment not found"})
+			return
+		}
+
+		comment.Depth = parent.Depth + 1
+		if comment.Depth > config.Get().Posts.CommentMaxDepth {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Maximum reply depth reached"})
+			return
+		}
+	}
+
+	created, err := repository.Create(r.Context(), comment)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create comment"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, created)
+}
+
+// Comments godoc
+// @Summary List comments of a post
+// @Description List the comments of a post, oldest first, with cursor pagination. In flat shape every comment is paginated; in tree shape top-level comments are paginated and each carries its nested replies
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param shape query string false "flat (default) or tree"
+// @Param limit query int false "Number of comments to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Comment] "Page of comments"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/comments [get]
+func CommentGetAll(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	postID := params["id"]
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	shape := r.URL.Query().Get("shape")
+	if shape != "" && shape != "flat" && shape != "tree" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "shape must be flat or tree"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	if !postExists(w, r, db, postID) {
+		return
+	}
+
+	repository := repositories.NewCommentsRepository(db)
+
+	if shape != "tree" {
+		comments, err := repository.FindManyByPostId(r.Context(), postID, after, limit+1)
+		if err != nil {
+			log.Error().Err(err).Msg("Failed to fetch comments")
+			responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch comments"})
+			return
+		}
+
+		responses.JsonResponse(w, http.StatusOK, newPage(comments, limit, commentPosition))
+		return
+	}
+
+	roots, err := repository.FindRootsByPostId(r.Context(), postID, after, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch comments")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch comments"})
+		return
+	}
+
+	page := newPage(roots, limit, commentPosition)
+
+	rootIDs := make([]string, len(page.Data))
+	for i, root := range page.Data {
+		rootIDs[i] = root.ID.String()
+	}
+
+	replies, err := repository.FindDescendants(r.Context(), rootIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch replies")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch comments"})
+		return
+	}
+
+	page.Data = buildCommentTree(page.Data, replies)
+	responses.JsonResponse(w, http.StatusOK, page)
+}
+
+// Comments godoc
+// @Summary Edit a comment
+// @Description Change the content of a comment. Only its author can edit it
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param commentId path string true "Comment ID"
+// @Param request body dto.CommentUpdateDTO true "New content"
+// @Success 200 {object} models.Comment "Updated comment"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Comment not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/comments/{commentId} [put]
+// @Security ApiKeyAuth
+func CommentUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+
+	var commentDTO dto.CommentUpdateDTO
+	if err := json.NewDecoder(r.Body).Decode(&commentDTO); err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		return
+	}
+
+	err = Validate.Struct(commentDTO)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Validation failed: %v", err)})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewCommentsRepository(db)
+	comment, ok := findComment(w, r, db, params["id"], params["commentId"])
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "You can only edit your own comments"})
+		return
+	}
+
+	updated, err := repository.Update(r.Context(), comment.ID.String(), commentDTO.Content)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update comment"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, updated)
+}
+
+// Comments godoc
+// @Summary Delete a comment
+// @Description Delete a comment and all of its replies. The comment's author and the post's author can delete it
+// @Tags Comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param commentId path string true "Comment ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 404 {object} map[string]string "Comment not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/comments/{commentId} [delete]
+// @Security ApiKeyAuth
+func CommentDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewCommentsRepository(db)
+	comment, ok := findComment(w, r, db, params["id"], params["commentId"])
+	if !ok {
+		return
+	}
+
+	if comment.UserID != userID {
+		post, err := repositories.NewPostsRepository(db).FindById(r.Context(), comment.PostID)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find post"})
+			return
+		}
+
+		if post == nil || post.UserID != userID {
+			responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "You can only delete your own comments or comments on your posts"})
+			return
+		}
+	}
+
+	err = repository.Delete(r.Context(), comment.ID.String())
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete comment"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+func commentPosition(comment models.Comment) cursor.Position {
+	return cursor.Position{CreatedAt: comment.CreatedAt, ID: comment.ID.String()}
+}
+
+// postExists writes a 404 and reports false when postID does not name a
+// post.
+func postExists(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, postID string) bool {
+	if _, err := uuid.Parse(postID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+		return false
+	}
+
+	post, err := repositories.NewPostsRepository(db).FindById(r.Context(), postID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find post"})
+		return false
+	}
+
+	if post == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+		return false
+	}
+
+	return true
+}
+
+// findComment loads a comment and checks it belongs to postID, writing a
+// 404 otherwise.
+func findComment(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, postID string, commentID string) (*models.Comment, bool) {
+	post, err := uuid.Parse(postID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Comment not found"})
+		return nil, false
+	}
+
+	if _, err := uuid.Parse(commentID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Comment not found"})
+		return nil, false
+	}
+
+	comment, err := repositories.NewCommentsRepository(db).FindById(r.Context(), commentID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find comment"})
+		return nil, false
+	}
+
+	if comment == nil || comment.PostID != post.String() {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Comment not found"})
+		return nil, false
+	}
+
+	return comment, true
+}
+
+// buildCommentTree nests replies, ordered oldest first, under the roots
+// they descend from.
+func buildCommentTree(roots []models.Comment, replies []models.Comment) []models.Comment {
+	children := make(map[string][]models.Comment)
+	for _, reply := range replies {
+		if reply.ParentID != nil {
+			children[*reply.ParentID] = append(children[*reply.ParentID], reply)
+		}
+	}
+
+	var attach func(comment models.Comment) models.Comment
+	attach = func(comment models.Comment) models.Comment {
+		for _, child := range children[comment.ID.String()] {
+			comment.Replies = append(comment.Replies, attach(child))
+		}
+		return comment
+	}
+
+	tree := make([]models.Comment, len(roots))
+	for i, root := range roots {
+		tree[i] = attach(root)
+	}
+	return tree
+}
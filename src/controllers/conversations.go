@@ -0,0 +1,588 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/controllers/dto"
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
+)
+
+// Conversations godoc
+// @Summary Start a conversation
+// @Description Start a conversation with one user, or a group with several. Starting a one-to-one conversation that already exists returns it, bringing back members who left. Every member must accept direct messages
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param request body dto.ConversationCreateDTO true "Users to talk to, not including yourself"
+// @Success 200 {object} models.Conversation "Existing conversation"
+// @Success 201 {object} models.Conversation "New conversation"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "A member does not accept direct messages"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations [post]
+// @Security ApiKeyAuth
+func ConversationCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	var conversationDTO dto.ConversationCreateDTO
+	if !decodeBody(w, r, &conversationDTO) {
+		return
+	}
+
+	memberIDs := []string{}
+	seen := map[string]bool{userID: true}
+	for _, id := range conversationDTO.MemberIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid member ID"})
+			return
+		}
+
+		id = parsed.String()
+		if !seen[id] {
+			seen[id] = true
+			memberIDs = append(memberIDs, id)
+		}
+	}
+
+	if len(memberIDs) == 0 {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "A conversation needs at least one other member"})
+		return
+	}
+
+	maxGroupSize := config.Get().Messages.MaxGroupSize
+	if len(memberIDs)+1 > maxGroupSize {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("A conversation can have at most %d members", maxGroupSize)})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	policies, err := repository.FindMessagePolicies(r.Context(), memberIDs)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to start conversation"})
+		return
+	}
+
+	for _, id := range memberIDs {
+		policy, ok := policies[id]
+		if !ok {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("User %s not found", id)})
+			return
+		}
+		if policy == models.MessagePolicyNobody {
+			responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": fmt.Sprintf("User %s does not accept direct messages", id)})
+			return
+		}
+	}
+
+	conversationID, created, err := repository.Start(r.Context(), userID, memberIDs)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start conversation")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to start conversation"})
+		return
+	}
+
+	conversation, err := repository.FindById(r.Context(), conversationID, userID)
+	if err != nil || conversation == nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch conversation"})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	responses.JsonResponse(w, status, conversation)
+}
+
+// Conversations godoc
+// @Summary List your conversations
+// @Description List the conversations the authenticated user has not left, most recently active first, with unread counts and the last message
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param limit query int false "Number of conversations to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Conversation] "Page of conversations"
+// @Failure 400 {object} map[string]string "Invalid cursor"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/conversations [get]
+// @Security ApiKeyAuth
+func ConversationGetAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	conversations, err := repository.FindManyByUserId(r.Context(), userID, after, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch conversations")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch conversations"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage(conversations, limit, conversationPosition))
+}
+
+func conversationPosition(conversation models.Conversation) cursor.Position {
+	return cursor.Position{CreatedAt: conversation.LastMessageAt, ID: conversation.ID.String()}
+}
+
+// Conversations godoc
+// @Summary Count unread messages
+// @Description Get how many messages the authenticated user has not read, across the conversations they have not muted
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]int "Returns unread"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/conversations/unread-count [get]
+// @Security ApiKeyAuth
+func ConversationUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	count, err := repository.CountUnread(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to count messages"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, map[string]int{"unread": count})
+}
+
+// Conversations godoc
+// @Summary Get a conversation
+// @Description Get a conversation the authenticated user is a member of
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 200 {object} models.Conversation "Conversation"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id} [get]
+// @Security ApiKeyAuth
+func ConversationGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	conversation, err := repository.FindById(r.Context(), id, userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch conversation"})
+		return
+	}
+
+	if conversation == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Conversation not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, conversation)
+}
+
+// Messages godoc
+// @Summary List messages
+// @Description List the messages of a conversation, newest first, with cursor pagination
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param limit query int false "Number of messages to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Message] "Page of messages"
+// @Failure 400 {object} map[string]string "Invalid cursor"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id}/messages [get]
+// @Security ApiKeyAuth
+func MessageGetAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	conversation, err := repository.FindById(r.Context(), id, userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch conversation"})
+		return
+	}
+
+	if conversation == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Conversation not found"})
+		return
+	}
+
+	messages, err := repository.FindMessages(r.Context(), id, after, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch messages")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch messages"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage(messages, limit, messagePosition))
+}
+
+func messagePosition(message models.Message) cursor.Position {
+	return cursor.Position{CreatedAt: message.CreatedAt, ID: message.ID.String()}
+}
+
+// Messages godoc
+// @Summary Send a message
+// @Description Send a message to a conversation the authenticated user is a member of
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Param request body dto.MessageCreateDTO true "Message"
+// @Success 201 {object} models.Message "Sent message"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id}/messages [post]
+// @Security ApiKeyAuth
+func MessageCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	var messageDTO dto.MessageCreateDTO
+	if !decodeBody(w, r, &messageDTO) {
+		return
+	}
+
+	content := strings.TrimSpace(messageDTO.Content)
+	if content == "" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Message is empty"})
+		return
+	}
+
+	maxLength := config.Get().Messages.MaxLength
+	if utf8.RuneCountInString(content) > maxLength {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Message is longer than %d characters", maxLength)})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	conversation, err := repository.FindById(r.Context(), id, userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch conversation"})
+		return
+	}
+
+	if conversation == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Conversation not found"})
+		return
+	}
+
+	message, err := repository.Send(r.Context(), id, userID, content)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send message")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to send message"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, message)
+}
+
+// Conversations godoc
+// @Summary Mark a conversation as read
+// @Description Mark every message of a conversation so far as read by the authenticated user
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id}/read [put]
+// @Security ApiKeyAuth
+func ConversationMarkRead(w http.ResponseWriter, r *http.Request) {
+	updateMembership(w, r, func(db *pgxpool.Pool, id string, userID string) (bool, error) {
+		return repositories.NewConversationsRepository(db).MarkRead(r.Context(), id, userID)
+	})
+}
+
+// Conversations godoc
+// @Summary Mute a conversation
+// @Description Mute a conversation for the authenticated user. Its messages no longer count towards the unread total
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id}/mute [put]
+// @Security ApiKeyAuth
+func ConversationMute(w http.ResponseWriter, r *http.Request) {
+	updateMembership(w, r, func(db *pgxpool.Pool, id string, userID string) (bool, error) {
+		return repositories.NewConversationsRepository(db).SetMuted(r.Context(), id, userID, true)
+	})
+}
+
+// Conversations godoc
+// @Summary Unmute a conversation
+// @Description Unmute a conversation for the authenticated user
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id}/mute [delete]
+// @Security ApiKeyAuth
+func ConversationUnmute(w http.ResponseWriter, r *http.Request) {
+	updateMembership(w, r, func(db *pgxpool.Pool, id string, userID string) (bool, error) {
+		return repositories.NewConversationsRepository(db).SetMuted(r.Context(), id, userID, false)
+	})
+}
+
+// Conversations godoc
+// @Summary Leave a conversation
+// @Description Leave a conversation. It disappears from your list until someone starts a one-to-one conversation with you again
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param id path string true "Conversation ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Conversation not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /conversations/{id}/leave [post]
+// @Security ApiKeyAuth
+func ConversationLeave(w http.ResponseWriter, r *http.Request) {
+	updateMembership(w, r, func(db *pgxpool.Pool, id string, userID string) (bool, error) {
+		return repositories.NewConversationsRepository(db).Leave(r.Context(), id, userID)
+	})
+}
+
+// updateMembership applies update to the authenticated user's membership
+// of the conversation in the path.
+func updateMembership(w http.ResponseWriter, r *http.Request, update func(db *pgxpool.Pool, id string, userID string) (bool, error)) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	id, ok := conversationID(w, r)
+	if !ok {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	found, err := update(db, id, userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update conversation"})
+		return
+	}
+
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Conversation not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// conversationID reads the conversation ID from the path, answering 404
+// when it is not a UUID.
+func conversationID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Conversation not found"})
+		return "", false
+	}
+	return id, true
+}
+
+// Messages godoc
+// @Summary Get your message settings
+// @Description Get who may start conversations with the authenticated user: everyone or nobody
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Success 200 {object} dto.MessageSettingsDTO "Message settings"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/message-settings [get]
+// @Security ApiKeyAuth
+func MessageSettingsGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	policies, err := repository.FindMessagePolicies(r.Context(), []string{userID})
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch message settings"})
+		return
+	}
+
+	policy, ok := policies[userID]
+	if !ok {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, dto.MessageSettingsDTO{AllowFrom: policy})
+}
+
+// Messages godoc
+// @Summary Update your message settings
+// @Description Set who may start conversations with the authenticated user: everyone or nobody. Existing conversations are not affected
+// @Tags Messages
+// @Accept json
+// @Produce json
+// @Param request body dto.MessageSettingsDTO true "Message settings"
+// @Success 200 {object} dto.MessageSettingsDTO "Message settings"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/message-settings [put]
+// @Security ApiKeyAuth
+func MessageSettingsSet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	var settingsDTO dto.MessageSettingsDTO
+	if !decodeBody(w, r, &settingsDTO) {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewConversationsRepository(db)
+	if err := repository.SetMessagePolicy(r.Context(), userID, settingsDTO.AllowFrom); err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update message settings"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, settingsDTO)
+}
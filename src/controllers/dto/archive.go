@@ -0,0 +1,6 @@
+package dto
+
+// TimeZoneDTO holds an IANA time zone name such as "Europe/Lisbon".
+type TimeZoneDTO struct {
+	TimeZone string `json:"time_zone" validate:"required,max=64"`
+}
@@ -0,0 +1,10 @@
+package dto
+
+type CommentCreateDTO struct {
+	Content  string  `json:"content" validate:"required,max=2000"`
+	ParentID *string `json:"parent_id" validate:"omitempty,uuid"`
+}
+
+type CommentUpdateDTO struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
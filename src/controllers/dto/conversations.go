@@ -0,0 +1,13 @@
+package dto
+
+type ConversationCreateDTO struct {
+	MemberIDs []string `json:"member_ids" validate:"required,min=1,dive,uuid"`
+}
+
+type MessageCreateDTO struct {
+	Content string `json:"content" validate:"required"`
+}
+
+type MessageSettingsDTO struct {
+	AllowFrom string `json:"allow_from" validate:"required,oneof=everyone nobody"`
+}
@@ -0,0 +1,6 @@
+package dto
+
+type BroadcastDTO struct {
+	Title   string `json:"title" validate:"required,max=100"`
+	Message string `json:"message" validate:"required,max=2000"`
+}
@@ -1,6 +1,15 @@
 package dto
 
+import "api/src/models"
+
 type PostCreateDTO struct {
-	Title   string `json:"title" validate:"required"`
-	Content string `json:"content" validate:"required"`
+	Title        string   `json:"title" validate:"required"`
+	Content      string   `json:"content" validate:"required"`
+	QuotedPostID *string  `json:"quoted_post_id" validate:"omitempty,uuid"`
+	MediaIDs     []string `json:"media_ids" validate:"omitempty,dive,uuid"`
+}
+
+type UserPostsDTO struct {
+	Author models.UserSummary `json:"author"`
+	Posts  []models.Posts     `json:"posts"`
 }
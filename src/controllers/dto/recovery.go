@@ -0,0 +1,14 @@
+package dto
+
+type RecoveryEmailDTO struct {
+	Email string `json:"email" validate:"required,email,max=100"`
+}
+
+type TokenDTO struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type RecoveryConfirmDTO struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,min=8"`
+}
@@ -0,0 +1,11 @@
+package dto
+
+// ProfileUpdateDTO changes the fields that are set. Empty strings clear
+// every field but the handle, which can be changed but not removed.
+type ProfileUpdateDTO struct {
+	Handle   *string `json:"handle" validate:"omitempty"`
+	Bio      *string `json:"bio" validate:"omitempty,max=160"`
+	Location *string `json:"location" validate:"omitempty,max=100"`
+	Website  *string `json:"website" validate:"omitempty,max=255"`
+	Pronouns *string `json:"pronouns" validate:"omitempty,max=40"`
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/database"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Followers godoc
+// @Summary Follow a user
+// @Description Make the authenticated user follow another user
+// @Tags Followers
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID to follow"
+// @Success 201 {object} map[string]string "Success message"
+// @Failure 400 {object} map[string]string "Cannot follow yourself"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "Already following"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/follow [post]
+// @Security ApiKeyAuth
+func UserFollow(w http.ResponseWriter, r *http.Request) {
+	followerID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	followedID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	if followerID == followedID {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "You cannot follow yourself"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), followedID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	repository := repositories.NewFollowersRepository(db)
+	created, err := repository.Follow(r.Context(), followerID, followedID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to follow user"})
+		return
+	}
+
+	if !created {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Already following this user"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, map[string]string{"message": "User followed successfully"})
+}
+
+// Followers godoc
+// @Summary Unfollow a user
+// @Description Make the authenticated user stop following another user
+// @Tags Followers
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID to unfollow"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found or not following this user"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/follow [delete]
+// @Security ApiKeyAuth
+func UserUnfollow(w http.ResponseWriter, r *http.Request) {
+	followerID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	followedID, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewFollowersRepository(db)
+	deleted, err := repository.Unfollow(r.Context(), followerID, followedID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to unfollow user"})
+		return
+	}
+
+	if !deleted {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Not following this user"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// Followers godoc
+// @Summary List followers
+// @Description List the users following a user, most recent first
+// @Tags Followers
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param limit query int false "Number of users to return (default 10)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {array} models.User "List of followers"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/followers [get]
+func UserFollowers(w http.ResponseWriter, r *http.Request) {
+	listFollows(w, r, true)
+}
+
+// Followers godoc
+// @Summary List followed users
+// @Description List the users a user follows, most recent first
+// @Tags Followers
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param limit query int false "Number of users to return (default 10)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {array} models.User "List of followed users"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/{id}/following [get]
+func UserFollowing(w http.ResponseWriter, r *http.Request) {
+	listFollows(w, r, false)
+}
+
+func listFollows(w http.ResponseWriter, r *http.Request, followers bool) {
+	id, ok := userIDParam(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := pagination(r)
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), id)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	repository := repositories.NewFollowersRepository(db)
+	list := repository.FindFollowing
+	if followers {
+		list = repository.FindFollowers
+	}
+
+	users, err := list(r.Context(), id, limit, offset)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve users"})
+		return
+	}
+
+	viewerID, _ := auth.ExtractUserID(r)
+	for i := range users {
+		users[i].HideEmail(viewerID)
+	}
+
+	responses.JsonResponse(w, http.StatusOK, users)
+}
+
+// userIDParam reads the {id} of a user route in canonical form, so that it
+// compares equal to IDs read from the database or a token. It writes a 404
+// for IDs that cannot exist.
+func userIDParam(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id, err := uuid.Parse(mux.Vars(r)["id"])
+	if err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return "", false
+	}
+	return id.String(), true
+}
@@ -1,67 +1,49 @@
 package controllers
 
 import (
-	"api/src/database"
+	"api/src/auth"
+	"api/src/health"
 	"api/src/responses"
-	"context"
 	"net/http"
 	"time"
 )
 
-// HealthCheck godoc
-// @Summary Health Check
-// @Description Get the health status of the application
+// HealthLive godoc
+// @Summary Liveness probe
+// @Description Reports whether the process is running. Does not check any dependency
 // @Tags Health-check
 // @Accept json
 // @Produce json
 // @Success 200
-// @Failure 503
-// @Router /health [get]
-func HealthCheck(w http.ResponseWriter, r *http.Request) {
-	status := map[string]interface{}{
-		"status":    "UP",
+// @Router /health/live [get]
+func HealthLive(w http.ResponseWriter, r *http.Request) {
+	responses.JsonResponse(w, http.StatusOK, map[string]string{
+		"status":    health.StatusUp,
 		"timestamp": time.Now().Format(time.RFC3339),
-	}
-
-	db, err := database.Connect()
-	if err != nil {
-		status["status"] = "DOWN"
-		status["database"] = map[string]string{
-			"status": "DOWN",
-			"error":  "Failed to connect to database",
-		}
-		responses.JsonResponse(w, http.StatusServiceUnavailable, status)
-		return
-	}
-	defer db.Close(context.Background())
-
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
-	defer cancel()
-
-	if err := db.Ping(ctx); err != nil {
-		status["status"] = "DOWN"
-		status["database"] = map[string]string{
-			"status": "DOWN",
-			"error":  "Database ping failed",
-		}
-		responses.JsonResponse(w, http.StatusServiceUnavailable, status)
-		return
-	}
+	})
+}
 
-	var dbVersion string
-	err = db.QueryRow(ctx, "SELECT version()").Scan(&dbVersion)
+// HealthReady godoc
+// @Summary Readiness probe
+// @Description Aggregates the registered dependency checks. The per-check breakdown is only included for admins
+// @Tags Health-check
+// @Accept json
+// @Produce json
+// @Success 200 {object} health.Report
+// @Failure 503 {object} health.Report
+// @Router /health [get]
+// @Router /health/ready [get]
+func HealthReady(w http.ResponseWriter, r *http.Request) {
+	report := health.Ready(r.Context())
 
-	dbStatus := map[string]string{
-		"status": "UP",
+	status := http.StatusOK
+	if report.Status != health.StatusUp {
+		status = http.StatusServiceUnavailable
 	}
 
-	if err != nil {
-		dbStatus["version_error"] = "Could not retrieve database version"
-	} else {
-		dbStatus["version"] = dbVersion
+	if !auth.IsAdmin(r) {
+		report.Checks = nil
 	}
 
-	status["database"] = dbStatus
-
-	responses.JsonResponse(w, http.StatusOK, status)
+	responses.JsonResponse(w, status, report)
 }
@@ -0,0 +1,229 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/database"
+	"api/src/media"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/storage"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// multipartOverhead is allowed on top of the file size for the rest of
+// the multipart body.
+const multipartOverhead = 64 << 10
+
+// Media godoc
+// @Summary Upload media
+// @Description Upload a JPEG, PNG, GIF or WebP image to attach to a post with media_ids. The type is detected from the content. Metadata such as EXIF is removed. Uploads not attached to a post are deleted after a while
+// @Tags Media
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "Image"
+// @Success 201 {object} models.Media "Uploaded media"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 413 {object} map[string]string "File too large"
+// @Failure 415 {object} map[string]string "Unsupported media type"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /media [post]
+// @Security ApiKeyAuth
+func MediaUpload(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	image, ok := readImage(w, r)
+	if !ok {
+		return
+	}
+
+	store, err := storage.Get()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Media storage is not available"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	upload := models.Media{
+		ID:       uuid.New(),
+		MimeType: image.MimeType,
+		Size:     int64(len(image.Data)),
+		Width:    image.Width,
+		Height:   image.Height,
+	}
+	upload.StorageKey = upload.ID.String() + image.Extension
+
+	if err := store.Put(r.Context(), upload.StorageKey, bytes.NewReader(image.Data), upload.Size, upload.MimeType); err != nil {
+		log.Error().Err(err).Msg("Failed to store media")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to store media"})
+		return
+	}
+
+	created, err := repositories.NewMediaRepository(db).Create(r.Context(), userID, upload)
+	if err != nil {
+		if err := store.Delete(r.Context(), upload.StorageKey); err != nil {
+			log.Error().Err(err).Str("key", upload.StorageKey).Msg("Failed to delete unrecorded media")
+		}
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save media"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, created)
+}
+
+// readImage reads and processes the image uploaded in the multipart
+// "file" field. It writes an error response and returns false when the
+// upload is missing, too large or not a supported image.
+func readImage(w http.ResponseWriter, r *http.Request) (*media.Image, bool) {
+	maxSize := config.Get().Media.MaxSize
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize)+multipartOverhead)
+
+	data, err := readUpload(r, maxSize)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || (err == nil && len(data) > maxSize) {
+		responses.JsonResponse(w, http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("File is larger than %d bytes", maxSize)})
+		return nil, false
+	}
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return nil, false
+	}
+
+	image, err := media.Process(data)
+	if errors.Is(err, media.ErrUnsupportedType) {
+		responses.JsonResponse(w, http.StatusUnsupportedMediaType, map[string]string{"error": "Only JPEG, PNG, GIF and WebP images are supported"})
+		return nil, false
+	}
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Malformed image"})
+		return nil, false
+	}
+
+	return image, true
+}
+
+// readUpload returns the content of the multipart "file" field, reading
+// at most one byte more than maxSize so oversized files can be told apart.
+func readUpload(r *http.Request, maxSize int) ([]byte, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, errors.New("Expected a multipart/form-data body")
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errors.New("Missing file field")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, int64(maxSize)+1))
+		part.Close()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			return nil, errors.New("File is empty")
+		}
+		return data, nil
+	}
+}
+
+// Media godoc
+// @Summary Get media
+// @Description Get the content of an uploaded image
+// @Tags Media
+// @Produce image/jpeg,image/png,image/gif,image/webp
+// @Param id path string true "Media ID"
+// @Success 200 {file} file "Image"
+// @Failure 404 {object} map[string]string "Media not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /media/{id} [get]
+func MediaGet(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Media not found"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	found, err := repositories.NewMediaRepository(db).FindById(r.Context(), id)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find media"})
+		return
+	}
+
+	if found == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Media not found"})
+		return
+	}
+
+	sendObject(w, r, found.StorageKey, found.MimeType, found.Size)
+}
+
+// sendObject writes a stored upload. Uploads never change, since a new
+// one gets a new key, so they can be cached for good. size is unknown
+// when negative.
+func sendObject(w http.ResponseWriter, r *http.Request, key string, contentType string, size int64) {
+	store, err := storage.Get()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Media storage is not available"})
+		return
+	}
+
+	content, err := store.Open(r.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Media not found"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Str("key", key).Msg("Failed to open media")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to read media"})
+		return
+	}
+	defer content.Close()
+
+	w.Header().Set("Content-Type", contentType)
+	if size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+	}
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, content); err != nil {
+		log.Warn().Err(err).Str("key", key).Msg("Failed to send media")
+	}
+}
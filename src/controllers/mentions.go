@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/database"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Mentions godoc
+// @Summary List posts mentioning you
+// @Description List the posts that mention the authenticated user with @handle, newest first, with cursor pagination
+// @Tags Mentions
+// @Accept json
+// @Produce json
+// @Param limit query int false "Number of posts to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Posts] "Page of posts"
+// @Failure 400 {object} map[string]string "Invalid cursor"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/mentions [get]
+// @Security ApiKeyAuth
+func MentionGetAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewPostsRepository(db)
+	posts, err := repository.FindTimeline(r.Context(), repositories.TimelineFilters{MentionedID: userID, After: after}, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch mentions")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch mentions"})
+		return
+	}
+
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch mentions"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage[models.Posts](posts, limit, postPosition))
+}
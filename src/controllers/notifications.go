@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/controllers/dto"
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"api/src/notify"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+// Notifications godoc
+// @Summary List your notifications
+// @Description List the authenticated user's notifications, newest first, with cursor pagination
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param kind query string false "Only notifications of this kind, such as security.login or broadcast"
+// @Param limit query int false "Number of notifications to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Notification] "Page of notifications"
+// @Failure 400 {object} map[string]string "Invalid cursor"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/notifications [get]
+// @Security ApiKeyAuth
+func NotificationGetAll(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewNotificationsRepository(db)
+	notifications, err := repository.FindManyByUserId(r.Context(), userID, r.URL.Query().Get("kind"), after, limit+1)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch notifications")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage(notifications, limit, notificationPosition))
+}
+
+func notificationPosition(notification models.Notification) cursor.Position {
+	return cursor.Position{CreatedAt: notification.CreatedAt, ID: notification.ID.String()}
+}
+
+// Notifications godoc
+// @Summary Count unread notifications
+// @Description Get how many of the authenticated user's notifications are unread
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]int "Returns unread"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/notifications/unread-count [get]
+// @Security ApiKeyAuth
+func NotificationUnreadCount(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewNotificationsRepository(db)
+	count, err := repository.CountUnread(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to count notifications"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, map[string]int{"unread": count})
+}
+
+// Notifications godoc
+// @Summary Mark a notification as read
+// @Description Mark one of the authenticated user's notifications as read. Marking it again has no effect
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param id path string true "Notification ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Notification not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/notifications/{id}/read [put]
+// @Security ApiKeyAuth
+func NotificationMarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+	id := params["id"]
+	if _, err := uuid.Parse(id); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Notification not found"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewNotificationsRepository(db)
+	found, err := repository.MarkRead(r.Context(), userID, id)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update notification"})
+		return
+	}
+
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Notification not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// Notifications godoc
+// @Summary Mark all notifications as read
+// @Description Mark every unread notification of the authenticated user as read
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]int64 "Returns updated, the number of notifications marked"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/notifications/read [put]
+// @Security ApiKeyAuth
+func NotificationMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewNotificationsRepository(db)
+	updated, err := repository.MarkAllRead(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update notifications"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, map[string]int64{"updated": updated})
+}
+
+// Notifications godoc
+// @Summary Broadcast a notification
+// @Description Send a notification to every user. Admins only
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Param request body dto.BroadcastDTO true "Notification to send"
+// @Success 201 {object} map[string]int64 "Returns sent, the number of users notified"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /notifications/broadcast [post]
+// @Security ApiKeyAuth
+func NotificationBroadcast(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r) {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "Forbidden"})
+		return
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
+		return
+	}
+
+	var broadcastDTO dto.BroadcastDTO
+	if err = json.Unmarshal(body, &broadcastDTO); err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Failed to unmarshal JSON"})
+		return
+	}
+
+	err = Validate.Struct(broadcastDTO)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Validation failed: %v", err)})
+		return
+	}
+
+	sent, err := notify.Broadcast(r.Context(), notify.KindBroadcast, broadcastDTO)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to broadcast notification")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to broadcast notification"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, map[string]int64{"sent": sent})
+}
@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"api/src/cursor"
+	"api/src/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// pagination reads the limit and page query parameters, defaulting to 10
+// items on page 1, and returns the matching limit and offset.
+func pagination(r *http.Request) (int, int) {
+	queryParams := r.URL.Query()
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	page, err := strconv.Atoi(queryParams.Get("page"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	return limit, (page - 1) * limit
+}
+
+const maxCursorLimit = 100
+
+// cursorPagination reads the limit and cursor query parameters for
+// cursor-paginated lists. The limit defaults to 20 and is capped at 100.
+func cursorPagination(r *http.Request) (int, *cursor.Position, error) {
+	queryParams := r.URL.Query()
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > maxCursorLimit {
+		limit = maxCursorLimit
+	}
+
+	raw := queryParams.Get("cursor")
+	if raw == "" {
+		return limit, nil, nil
+	}
+
+	position, err := cursor.Decode(raw)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return limit, &position, nil
+}
+
+// newPage builds a page from up to limit+1 items, using the extra item
+// only to tell whether another page exists.
+func newPage[T any](items []T, limit int, position func(T) cursor.Position) models.Page[T] {
+	page := models.Page[T]{Data: items}
+	if len(items) > limit {
+		page.Data = items[:limit]
+		page.NextCursor = cursor.Encode(position(page.Data[limit-1]))
+	}
+	return page
+}
+
+// parseDate accepts either an RFC 3339 timestamp or a plain YYYY-MM-DD
+// date. endOfDay moves plain dates to the start of the following day, so
+// they can be used as exclusive upper bounds.
+func parseDate(value string, endOfDay bool) (*time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		t = t.UTC()
+		return &t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
@@ -2,24 +2,28 @@ package controllers
 
 import (
 	"api/src/auth"
+	"api/src/config"
 	"api/src/controllers/dto"
+	"api/src/cursor"
 	"api/src/database"
 	"api/src/models"
 	"api/src/repositories"
 	"api/src/responses"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strconv"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/rs/zerolog/log"
 )
 
 // Posts godoc
 // @Summary Create a new post
-// @Description Create a new post with title and content
+// @Description Create a new post with title and content. Set quoted_post_id to quote another post, and media_ids to attach your uploads from POST /media in order
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -55,10 +59,33 @@ func PostCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	mediaIDs := []string{}
+	seen := map[string]bool{}
+	for _, id := range postDTO.MediaIDs {
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid media ID"})
+			return
+		}
+
+		id = parsed.String()
+		if !seen[id] {
+			seen[id] = true
+			mediaIDs = append(mediaIDs, id)
+		}
+	}
+
+	maxMedia := config.Get().Media.MaxPerPost
+	if len(mediaIDs) > maxMedia {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("A post can have at most %d media", maxMedia)})
+		return
+	}
+
 	post := models.Posts{
-		Title:   postDTO.Title,
-		Content: postDTO.Content,
-		UserID:  userId,
+		Title:        postDTO.Title,
+		Content:      postDTO.Content,
+		UserID:       userId,
+		QuotedPostID: postDTO.QuotedPostID,
 	}
 
 	db, err := database.Connect()
@@ -69,7 +96,24 @@ func PostCreate(w http.ResponseWriter, r *http.Request) {
 
 	repository := repositories.NewPostsRepository(db)
 
-	postID, err := repository.Create(post)
+	if post.QuotedPostID != nil {
+		quoted, err := repository.FindById(r.Context(), *post.QuotedPostID)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find quoted post"})
+			return
+		}
+
+		if quoted == nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Quoted post not found"})
+			return
+		}
+	}
+
+	postID, err := repository.Create(r.Context(), post, mediaIDs)
+	if errors.Is(err, repositories.ErrMediaUnavailable) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Media not found or already attached"})
+		return
+	}
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create post"})
 		return
@@ -80,7 +124,7 @@ func PostCreate(w http.ResponseWriter, r *http.Request) {
 
 // Posts godoc
 // @Summary Get all posts by user ID
-// @Description Get all posts created by the authenticated user with pagination and filtering
+// @Description Get all posts and reposts by the authenticated user with pagination and filtering
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -100,19 +144,99 @@ func PostGetAllByUserId(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	queryParams := r.URL.Query()
+	limit, offset := pagination(r)
+	filters := postFilters(r)
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewPostsRepository(db)
+	posts, err := repository.FindManyByUserId(r.Context(), userID, limit, offset, filters)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch posts")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, posts)
+}
+
+// Posts godoc
+// @Summary Get all posts by a user
+// @Description Get the posts and reposts of any user with pagination and filtering, along with a summary of the author
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param limit query int false "Number of posts to return (default 10)"
+// @Param page query int false "Page number (default 1)"
+// @Param title query string false "Filter by title"
+// @Param content query string false "Filter by content"
+// @Success 200 {object} dto.UserPostsDTO "Author summary and list of posts"
+// @Failure 404 "User not found"
+// @Failure 500 "Internal server error"
+// @Router /users/{id}/posts [get]
+func PostGetAllByAuthor(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	userID := params["id"]
 
-	limit, err := strconv.Atoi(queryParams.Get("limit"))
-	if err != nil || limit < 0 {
-		limit = 10
+	if _, err := uuid.Parse(userID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	limit, offset := pagination(r)
+	filters := postFilters(r)
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	repository := repositories.NewPostsRepository(db)
+	posts, err := repository.FindManyByUserId(r.Context(), userID, limit, offset, filters)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch posts")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
 	}
 
-	page, err := strconv.Atoi(queryParams.Get("page"))
-	if err != nil || page < 1 {
-		page = 1
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
 	}
 
-	offset := (page - 1) * limit
+	responses.JsonResponse(w, http.StatusOK, dto.UserPostsDTO{
+		Author: user.Summary(),
+		Posts:  posts,
+	})
+}
+
+// postFilters reads the title and content filters shared by the post
+// listings.
+func postFilters(r *http.Request) map[string]interface{} {
+	queryParams := r.URL.Query()
 
 	filters := make(map[string]interface{})
 	if title := queryParams.Get("title"); title != "" {
@@ -123,6 +247,65 @@ func PostGetAllByUserId(w http.ResponseWriter, r *http.Request) {
 		filters["content"] = content
 	}
 
+	return filters
+}
+
+// Posts godoc
+// @Summary Get the public timeline
+// @Description Get posts from every user, newest first, with cursor pagination. Use since_id to fetch only posts newer than the last one seen
+// @Tags Posts
+// @Accept json
+// @Produce json
+// @Param limit query int false "Number of posts to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Param author_id query string false "Only posts by this user"
+// @Param from query string false "Only posts created at or after this date (YYYY-MM-DD or RFC 3339)"
+// @Param to query string false "Only posts created before the end of this date (YYYY-MM-DD or RFC 3339)"
+// @Param since_id query string false "Only posts newer than this post"
+// @Success 200 {object} models.Page[models.Posts] "Page of posts"
+// @Failure 400 "Bad request"
+// @Failure 500 "Internal server error"
+// @Router /posts [get]
+func PostGetTimeline(w http.ResponseWriter, r *http.Request) {
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	queryParams := r.URL.Query()
+	filters := repositories.TimelineFilters{After: after}
+
+	if authorID := queryParams.Get("author_id"); authorID != "" {
+		if _, err := uuid.Parse(authorID); err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid author_id"})
+			return
+		}
+		filters.AuthorID = authorID
+	}
+
+	if sinceID := queryParams.Get("since_id"); sinceID != "" {
+		if _, err := uuid.Parse(sinceID); err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid since_id"})
+			return
+		}
+		filters.SinceID = sinceID
+	}
+
+	if from := queryParams.Get("from"); from != "" {
+		if filters.From, err = parseDate(from, false); err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid from date"})
+			return
+		}
+	}
+
+	if to := queryParams.Get("to"); to != "" {
+		if filters.To, err = parseDate(to, true); err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid to date"})
+			return
+		}
+	}
+
 	db, err := database.Connect()
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
@@ -130,13 +313,24 @@ func PostGetAllByUserId(w http.ResponseWriter, r *http.Request) {
 	}
 
 	repository := repositories.NewPostsRepository(db)
-	posts, err := repository.FindManyByUserId(userID, limit, offset, filters)
+	posts, err := repository.FindTimeline(r.Context(), filters, limit+1)
 	if err != nil {
-		log.Error().Err(err).Msg("Failed to fetch posts")
+		log.Error().Err(err).Msg("Failed to fetch timeline")
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
 		return
 	}
-	responses.JsonResponse(w, http.StatusOK, posts)
+
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage(posts, limit, postPosition))
+}
+
+func postPosition(post models.Posts) cursor.Position {
+	return cursor.Position{CreatedAt: post.CreatedAt, ID: post.ID.String()}
 }
 
 // Posts godoc
@@ -147,6 +341,7 @@ func PostGetAllByUserId(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param id path string true "Post ID"
 // @Success 200 {object} models.Posts "Post details"
+// @Failure 404 "Post not found"
 // @Failure 500 "Internal server error"
 // @Router /posts/{id} [get]
 func PostGetOne(w http.ResponseWriter, r *http.Request) {
@@ -160,17 +355,30 @@ func PostGetOne(w http.ResponseWriter, r *http.Request) {
 	}
 
 	repository := repositories.NewPostsRepository(db)
-	post, err := repository.FindById(id)
+	post, err := repository.FindById(r.Context(), id)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find post"})
 		return
 	}
-	responses.JsonResponse(w, http.StatusOK, post)
+
+	if post == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+		return
+	}
+
+	posts := []models.Posts{*post}
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find post"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, posts[0])
 }
 
 // Posts godoc
 // @Summary Update a post
-// @Description Update a post with the provided fields
+// @Description Change the title or content of a post. Only its author can edit it
 // @Tags Posts
 // @Accept json
 // @Produce json
@@ -179,11 +387,13 @@ func PostGetOne(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {object} models.Posts "Updated post"
 // @Failure 400 "Bad request"
 // @Failure 401 "Unauthorized"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Post not found"
 // @Failure 500 "Internal server error"
 // @Router /posts/{id} [put]
 // @Security ApiKeyAuth
 func PostUpdate(w http.ResponseWriter, r *http.Request) {
-	_, err := auth.ExtractUserID(r)
+	userID, err := auth.ExtractUserID(r)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 		return
@@ -198,35 +408,74 @@ func PostUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for key, value := range fields {
+		if text, ok := value.(string); !ok || text == "" {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s must be a non-empty string", key)})
+			return
+		}
+	}
+
 	db, err := database.Connect()
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
 		return
 	}
 
+	post, ok := findPost(w, r, db, id)
+	if !ok {
+		return
+	}
+
+	if post.UserID != userID {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "You can only edit your own posts"})
+		return
+	}
+
 	repository := repositories.NewPostsRepository(db)
-	updatedPost, err := repository.Update(id, fields)
+	updatedPost, err := repository.Update(r.Context(), post.ID.String(), fields)
+	if errors.Is(err, repositories.ErrFieldNotAllowed) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Only title and content can be updated"})
+		return
+	}
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update post"})
 		return
 	}
-	responses.JsonResponse(w, http.StatusOK, updatedPost)
+
+	// Update changes nothing for an empty body, and finds nothing when the
+	// post was deleted meanwhile.
+	if updatedPost == nil {
+		if len(fields) > 0 {
+			responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+			return
+		}
+		updatedPost = post
+	}
+
+	posts := []models.Posts{*updatedPost}
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+	}
+
+	responses.JsonResponse(w, http.StatusOK, posts[0])
 }
 
 // Posts godoc
 // @Summary Delete a post
-// @Description Delete a post by ID
+// @Description Delete a post with its comments, reactions and attachments. Only its author or an admin can delete it
 // @Tags Posts
 // @Accept json
 // @Produce json
 // @Param id path string true "Post ID"
 // @Success 204 "No content"
 // @Failure 401 "Unauthorized"
+// @Failure 403 "Forbidden"
+// @Failure 404 "Post not found"
 // @Failure 500 "Internal server error"
 // @Router /posts/{id} [delete]
 // @Security ApiKeyAuth
 func PostDelete(w http.ResponseWriter, r *http.Request) {
-	_, err := auth.ExtractUserID(r)
+	userID, err := auth.ExtractUserID(r)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
 		return
@@ -241,11 +490,91 @@ func PostDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	post, ok := findPost(w, r, db, id)
+	if !ok {
+		return
+	}
+
+	if post.UserID != userID && !auth.IsAdmin(r) {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "You can only delete your own posts"})
+		return
+	}
+
 	repository := repositories.NewPostsRepository(db)
-	err = repository.Delete(id)
+	err = repository.Delete(r.Context(), post.ID.String())
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete post"})
 		return
 	}
+
 	responses.JsonResponse(w, http.StatusNoContent, nil)
 }
+
+// findPost looks up the post a request is about to change, writing a 404
+// and returning false when there is no such post.
+func findPost(w http.ResponseWriter, r *http.Request, db *pgxpool.Pool, id string) (*models.Posts, bool) {
+	if _, err := uuid.Parse(id); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+		return nil, false
+	}
+
+	post, err := repositories.NewPostsRepository(db).FindById(r.Context(), id)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find post"})
+		return nil, false
+	}
+
+	if post == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Post not found"})
+		return nil, false
+	}
+
+	return post, true
+}
+
+// viewerID returns the ID of the authenticated user, or an empty string
+// for anonymous requests to public endpoints.
+func viewerID(r *http.Request) string {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		return ""
+	}
+	return userID
+}
+
+// withViewerState fills the fields of posts that depend on who is asking.
+// Anonymous viewers get empty values.
+func withViewerState(r *http.Request, db *pgxpool.Pool, posts []models.Posts) error {
+	for i := range posts {
+		posts[i].MyReactions = []string{}
+	}
+
+	userID := viewerID(r)
+	if userID == "" || len(posts) == 0 {
+		return nil
+	}
+
+	postIDs := make([]string, len(posts))
+	for i, post := range posts {
+		postIDs[i] = post.ID.String()
+	}
+
+	reactions, err := repositories.NewReactionsRepository(db).FindTypesByUser(r.Context(), userID, postIDs)
+	if err != nil {
+		return err
+	}
+
+	saved, err := repositories.NewBookmarksRepository(db).FindSavedByUser(r.Context(), userID, postIDs)
+	if err != nil {
+		return err
+	}
+
+	for i, post := range posts {
+		if types, ok := reactions[post.ID.String()]; ok {
+			posts[i].MyReactions = types
+		}
+		posts[i].Bookmarked = saved[post.ID.String()]
+	}
+
+	return nil
+}
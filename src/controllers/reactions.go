@@ -0,0 +1,138 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/database"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+	"slices"
+
+	"github.com/gorilla/mux"
+)
+
+// Reactions godoc
+// @Summary React to a post
+// @Description Add a reaction to a post. Adding the same reaction again has no effect
+// @Tags Reactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param type path string true "Reaction type, one of the configured reactions"
+// @Success 204 "No content"
+// @Failure 400 {object} map[string]string "Unknown reaction type"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/reactions/{type} [put]
+// @Security ApiKeyAuth
+func ReactionAdd(w http.ResponseWriter, r *http.Request) {
+	setReaction(w, r, true)
+}
+
+// Reactions godoc
+// @Summary Remove a reaction from a post
+// @Description Remove one of your reactions from a post. Removing a reaction you have not added has no effect
+// @Tags Reactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param type path string true "Reaction type, one of the configured reactions"
+// @Success 204 "No content"
+// @Failure 400 {object} map[string]string "Unknown reaction type"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/reactions/{type} [delete]
+// @Security ApiKeyAuth
+func ReactionRemove(w http.ResponseWriter, r *http.Request) {
+	setReaction(w, r, false)
+}
+
+// Reactions godoc
+// @Summary List reactions to a post
+// @Description List who reacted to a post, most recent first, optionally for a single reaction type
+// @Tags Reactions
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param type query string false "Only this reaction type"
+// @Param limit query int false "Number of reactions to return (default 10)"
+// @Param page query int false "Page number (default 1)"
+// @Success 200 {array} models.Reaction "List of reactions"
+// @Failure 400 {object} map[string]string "Unknown reaction type"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/reactions [get]
+func ReactionGetAll(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	postID := params["id"]
+
+	reactionType := r.URL.Query().Get("type")
+	if reactionType != "" && !slices.Contains(config.Get().Posts.Reactions, reactionType) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Unknown reaction type"})
+		return
+	}
+
+	limit, offset := pagination(r)
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	if !postExists(w, r, db, postID) {
+		return
+	}
+
+	repository := repositories.NewReactionsRepository(db)
+	reactions, err := repository.FindManyByPostId(r.Context(), postID, reactionType, limit, offset)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch reactions"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, reactions)
+}
+
+func setReaction(w http.ResponseWriter, r *http.Request, add bool) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+	postID := params["id"]
+	reactionType := params["type"]
+
+	if !slices.Contains(config.Get().Posts.Reactions, reactionType) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Unknown reaction type"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	if !postExists(w, r, db, postID) {
+		return
+	}
+
+	repository := repositories.NewReactionsRepository(db)
+	if add {
+		err = repository.Add(r.Context(), postID, userID, reactionType)
+	} else {
+		err = repository.Remove(r.Context(), postID, userID, reactionType)
+	}
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update reaction"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
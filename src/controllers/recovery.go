@@ -0,0 +1,481 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/controllers/dto"
+	"api/src/database"
+	"api/src/mailer"
+	"api/src/notify"
+	"api/src/repositories"
+	"api/src/responses"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RecoveryEmail godoc
+// @Summary Get your recovery email
+// @Description Get the verified secondary email that can be used to recover the account
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Success 200 {object} models.RecoveryEmail "Recovery email"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "No recovery email set"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/recovery-email [get]
+// @Security ApiKeyAuth
+func RecoveryEmailGet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewRecoveryRepository(db)
+	email, err := repository.FindEmail(r.Context(), userID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find recovery email"})
+		return
+	}
+
+	if email == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "No recovery email set"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, email)
+}
+
+// RecoveryEmail godoc
+// @Summary Set your recovery email
+// @Description Send a verification token to a secondary email. It becomes the recovery email once verified, replacing the current one
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Param request body dto.RecoveryEmailDTO true "Recovery email"
+// @Success 202 {object} map[string]string "Verification email sent"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 409 {object} map[string]string "Email already in use"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/recovery-email [put]
+// @Security ApiKeyAuth
+func RecoveryEmailSet(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	var emailDTO dto.RecoveryEmailDTO
+	if !decodeBody(w, r, &emailDTO) {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	// The address must be free to become the primary email on recovery.
+	owner, err := repositories.NewUsersRepository(db).FindByEmail(r.Context(), emailDTO.Email)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if owner != nil {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Email already in use"})
+		return
+	}
+
+	token, tokenHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		return
+	}
+
+	cfg := config.Get().Recovery
+	repository := repositories.NewRecoveryRepository(db)
+	if err := repository.SaveVerification(r.Context(), userID, emailDTO.Email, tokenHash, cfg.TokenTTL); err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to save recovery email"})
+		return
+	}
+
+	err = mailer.Send(r.Context(), mailer.Message{
+		To:      emailDTO.Email,
+		Subject: "Verify your recovery email",
+		Body: fmt.Sprintf("Someone asked to use this address to recover their DevBook account.\n\n"+
+			"If it was you, verify it by sending this token to POST /api/recovery-email/verify:\n\n%s\n\n"+
+			"The token expires in %s. If it was not you, ignore this email.\n", token, cfg.TokenTTL),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send recovery email verification")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to send verification email"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusAccepted, map[string]string{"message": "Verification email sent"})
+}
+
+// RecoveryEmail godoc
+// @Summary Verify a recovery email
+// @Description Confirm a recovery email with the token sent to it
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Param request body dto.TokenDTO true "Verification token"
+// @Success 200 {object} map[string]string "Recovery email verified"
+// @Failure 400 {object} map[string]string "Invalid or expired token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /recovery-email/verify [post]
+func RecoveryEmailVerify(w http.ResponseWriter, r *http.Request) {
+	var tokenDTO dto.TokenDTO
+	if !decodeBody(w, r, &tokenDTO) {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewRecoveryRepository(db)
+	userID, email, err := repository.VerifyEmail(r.Context(), auth.HashToken(tokenDTO.Token))
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to verify recovery email"})
+		return
+	}
+
+	if userID == "" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid or expired token"})
+		return
+	}
+
+	notifyPrimary(r, userID, notify.KindRecoveryEmailChanged, map[string]string{"recovery_email": email},
+		"Your recovery email changed",
+		fmt.Sprintf("%s is now the recovery email of your DevBook account. If you did not do this, change your password.\n", email))
+
+	responses.JsonResponse(w, http.StatusOK, map[string]string{"message": "Recovery email verified"})
+}
+
+// RecoveryEmail godoc
+// @Summary Remove your recovery email
+// @Description Remove the recovery email and cancel any pending recovery
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/recovery-email [delete]
+// @Security ApiKeyAuth
+func RecoveryEmailRemove(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewRecoveryRepository(db)
+	if err := repository.RemoveEmail(r.Context(), userID); err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to remove recovery email"})
+		return
+	}
+
+	if _, err := notify.Publish(r.Context(), userID, notify.KindRecoveryEmailChanged, map[string]interface{}{"recovery_email": nil}); err != nil {
+		log.Error().Err(err).Msg("Failed to publish recovery email notification")
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
+
+// AccountRecovery godoc
+// @Summary Start an account recovery
+// @Description Start recovering an account through its verified recovery email. A confirmation token is sent to the recovery email and can be used once the cooling-off delay has passed. The primary email is told about every attempt and gets a token to cancel it. The response is the same whether or not the email matches an account
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Param request body dto.RecoveryEmailDTO true "Recovery email of the account"
+// @Success 202 {object} map[string]string "Recovery started if the email matches an account"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /account-recovery [post]
+func AccountRecoveryStart(w http.ResponseWriter, r *http.Request) {
+	var emailDTO dto.RecoveryEmailDTO
+	if !decodeBody(w, r, &emailDTO) {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	accepted := map[string]string{"message": "If the email belongs to an account, recovery instructions were sent to it"}
+
+	repository := repositories.NewRecoveryRepository(db)
+	user, err := repository.FindUserByEmail(r.Context(), emailDTO.Email)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+
+	if user == nil {
+		responses.JsonResponse(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	confirmToken, confirmHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		return
+	}
+	cancelToken, cancelHash, err := auth.NewOpaqueToken()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		return
+	}
+
+	cfg := config.Get().Recovery
+	recovery, err := repository.Start(r.Context(), user.ID.String(), emailDTO.Email, confirmHash, cancelHash, cfg.Delay, cfg.TokenTTL)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to start account recovery")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to start recovery"})
+		return
+	}
+
+	wait := time.Until(recovery.AvailableAt).Round(time.Minute)
+	if recovery.Ready {
+		wait = 0
+	}
+
+	err = mailer.Send(r.Context(), mailer.Message{
+		To:      emailDTO.Email,
+		Subject: "Recover your DevBook account",
+		Body: fmt.Sprintf("A recovery of your DevBook account was requested.\n\n"+
+			"For your security it can be completed in %s. Then send this token with a new password to POST /api/account-recovery/confirm:\n\n%s\n\n"+
+			"Your primary email was told about this request and can cancel it.\n", formatWait(wait), confirmToken),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send account recovery email")
+	}
+
+	notifyPrimary(r, user.ID.String(), notify.KindRecoveryRequested, map[string]interface{}{"available_at": recovery.AvailableAt, "ip": clientIP(r)},
+		"Someone is trying to recover your DevBook account",
+		fmt.Sprintf("A recovery of your DevBook account through %s was requested from %s.\n\n"+
+			"If it was you, there is nothing to do. Otherwise cancel it by sending this token to POST /api/account-recovery/cancel:\n\n%s\n\n"+
+			"It can be completed in %s unless you cancel it.\n", emailDTO.Email, clientIP(r), cancelToken, formatWait(wait)))
+
+	responses.JsonResponse(w, http.StatusAccepted, accepted)
+}
+
+// AccountRecovery godoc
+// @Summary Confirm an account recovery
+// @Description Complete a recovery with the token sent to the recovery email once the cooling-off delay has passed. The recovery email becomes the primary email, the password is replaced and a JWT is returned
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Param request body dto.RecoveryConfirmDTO true "Recovery token and new password"
+// @Success 200 {object} map[string]string "Returns authentication token"
+// @Failure 400 {object} map[string]string "Invalid or expired token"
+// @Failure 409 {object} map[string]string "Recovery not available yet, or email already in use"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /account-recovery/confirm [post]
+func AccountRecoveryConfirm(w http.ResponseWriter, r *http.Request) {
+	var confirmDTO dto.RecoveryConfirmDTO
+	if !decodeBody(w, r, &confirmDTO) {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewRecoveryRepository(db)
+	recovery, err := repository.FindByConfirmToken(r.Context(), auth.HashToken(confirmDTO.Token))
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find recovery"})
+		return
+	}
+
+	if recovery == nil || recovery.Status != repositories.RecoveryPending || recovery.Expired {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid or expired token"})
+		return
+	}
+
+	if !recovery.Ready {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Recovery is not available yet", "available_at": recovery.AvailableAt.Format(time.RFC3339)})
+		return
+	}
+
+	owner, err := repositories.NewUsersRepository(db).FindByEmail(r.Context(), recovery.Email)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return
+	}
+	if owner != nil && owner.ID.String() != recovery.UserID {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Email already in use"})
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(confirmDTO.Password), bcrypt.DefaultCost)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate password hash"})
+		return
+	}
+
+	previousEmail, err := repository.Complete(r.Context(), recovery.ID.String(), string(passwordHash))
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to complete account recovery")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to complete recovery"})
+		return
+	}
+
+	if previousEmail == "" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid or expired token"})
+		return
+	}
+
+	if _, err := notify.Publish(r.Context(), recovery.UserID, notify.KindRecoveryCompleted, map[string]string{"email": recovery.Email, "ip": clientIP(r)}); err != nil {
+		log.Error().Err(err).Msg("Failed to publish recovery notification")
+	}
+
+	err = mailer.Send(r.Context(), mailer.Message{
+		To:      previousEmail,
+		Subject: "Your DevBook account was recovered",
+		Body:    fmt.Sprintf("Your DevBook account was recovered through %s, which is now its primary email.\n", recovery.Email),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to send account recovery email")
+	}
+
+	token, err := auth.GenerateToken(recovery.UserID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, map[string]string{"token": token})
+}
+
+// AccountRecovery godoc
+// @Summary Cancel an account recovery
+// @Description Cancel a pending recovery with the token sent to the primary email
+// @Tags Account recovery
+// @Accept json
+// @Produce json
+// @Param request body dto.TokenDTO true "Cancellation token"
+// @Success 200 {object} map[string]string "Recovery cancelled"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 404 {object} map[string]string "No pending recovery for this token"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /account-recovery/cancel [post]
+func AccountRecoveryCancel(w http.ResponseWriter, r *http.Request) {
+	var tokenDTO dto.TokenDTO
+	if !decodeBody(w, r, &tokenDTO) {
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewRecoveryRepository(db)
+	recovery, err := repository.Cancel(r.Context(), auth.HashToken(tokenDTO.Token))
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to cancel recovery"})
+		return
+	}
+
+	if recovery == nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "No pending recovery for this token"})
+		return
+	}
+
+	if _, err := notify.Publish(r.Context(), recovery.UserID, notify.KindRecoveryCancelled, map[string]string{"email": recovery.Email}); err != nil {
+		log.Error().Err(err).Msg("Failed to publish recovery notification")
+	}
+
+	responses.JsonResponse(w, http.StatusOK, map[string]string{"message": "Recovery cancelled"})
+}
+
+// decodeBody reads a JSON body into dst and validates it, writing a 400
+// response and returning false when either fails.
+func decodeBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Failed to read request body"})
+		return false
+	}
+
+	if err := json.Unmarshal(body, dst); err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Failed to unmarshal JSON"})
+		return false
+	}
+
+	if err := Validate.Struct(dst); err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Validation failed: %v", err)})
+		return false
+	}
+
+	return true
+}
+
+// notifyPrimary tells a user about a security event both in the app and
+// by email to their primary address. Failures are logged, not returned.
+func notifyPrimary(r *http.Request, userID string, kind string, payload interface{}, subject string, body string) {
+	if _, err := notify.Publish(r.Context(), userID, kind, payload); err != nil {
+		log.Error().Err(err).Str("kind", kind).Msg("Failed to publish notification")
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to connect to database")
+		return
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(r.Context(), userID)
+	if err != nil || user == nil {
+		log.Error().Err(err).Str("user_id", userID).Msg("Failed to find user to email")
+		return
+	}
+
+	if err := mailer.Send(r.Context(), mailer.Message{To: user.Email, Subject: subject, Body: body}); err != nil {
+		log.Error().Err(err).Str("kind", kind).Msg("Failed to send notification email")
+	}
+}
+
+// formatWait describes a delay for an email, such as "72h0m" or "now".
+func formatWait(d time.Duration) string {
+	if d <= 0 {
+		return "now"
+	}
+	return strings.TrimSuffix(d.String(), "0s")
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/database"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Reposts godoc
+// @Summary Repost a post
+// @Description Share a post on the authenticated user's profile
+// @Tags Reposts
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 201 {object} map[string]string "Returns repost_id and success message"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Post not found"
+// @Failure 409 {object} map[string]string "Already reposted"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/repost [post]
+// @Security ApiKeyAuth
+func RepostCreate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+	postID := params["id"]
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	if !postExists(w, r, db, postID) {
+		return
+	}
+
+	repository := repositories.NewRepostsRepository(db)
+	repostID, err := repository.Create(r.Context(), userID, postID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to repost"})
+		return
+	}
+
+	if repostID == "" {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Post already reposted"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusCreated, map[string]string{"message": "Post reposted successfully", "repost_id": repostID})
+}
+
+// Reposts godoc
+// @Summary Undo a repost
+// @Description Remove a repost from the authenticated user's profile. Works even if the original post was deleted
+// @Tags Reposts
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "Repost not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /posts/{id}/repost [delete]
+// @Security ApiKeyAuth
+func RepostDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	params := mux.Vars(r)
+	postID := params["id"]
+
+	if _, err := uuid.Parse(postID); err != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Repost not found"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewRepostsRepository(db)
+	deleted, err := repository.Delete(r.Context(), userID, postID)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to undo repost"})
+		return
+	}
+
+	if !deleted {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "Repost not found"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/responses"
+	"api/src/socket"
+	"net/http"
+)
+
+// Socket godoc
+// @Summary Open a WebSocket
+// @Description Upgrades to a WebSocket carrying JSON messages. Send {"type":"subscribe","id":"1","topic":"posts:new"} to subscribe to a topic (posts:new, post:{id} or user:{id}:posts), "unsubscribe" to leave it and "ping" to get a "pong". Replies are "ack" or "error" with the request id; published events arrive as {"type":"event","topic":...,"event":"post.created","data":{...}}. Authenticate with the Authorization header, or the access_token query parameter or cookie for browsers. Clients that fall behind are disconnected with close code 1013 and may reconnect
+// @Tags Stream
+// @Param access_token query string false "JWT, when the Authorization header cannot be used"
+// @Success 101 "Switching protocols"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Router /ws [get]
+func Socket(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDAnywhere(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return
+	}
+
+	socket.Default().Serve(w, r, userID)
+}
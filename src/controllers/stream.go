@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/responses"
+	"api/src/stream"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const maxWatchedPosts = 100
+
+// Stream godoc
+// @Summary Stream live post updates
+// @Description Server-Sent Events stream of new posts, and of updates and deletions of the posts listed in watch. Authenticate with the Authorization header, or the access_token query parameter or cookie for clients that cannot set headers. Reconnect with Last-Event-ID to replay missed events; a reset event means some were lost and the client should refetch
+// @Tags Stream
+// @Produce text/event-stream
+// @Param watch query string false "Comma-separated IDs of posts to receive updates and deletions for (max 100)"
+// @Param access_token query string false "JWT, when the Authorization header cannot be used"
+// @Param last_event_id query int false "Resume after this event, when the Last-Event-ID header cannot be used"
+// @Success 200 {string} string "Event stream"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 429 {object} map[string]string "Too many open streams"
+// @Router /stream [get]
+func Stream(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserIDAnywhere(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Authentication required"})
+		return
+	}
+
+	watch, err := watchedPosts(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var lastEventID *uint64
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	if raw != "" {
+		id, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		lastEventID = &id
+	}
+
+	sub, replay, truncated, err := stream.Default().Subscribe(userID, watch, lastEventID)
+	if errors.Is(err, stream.ErrTooManyConnections) {
+		responses.JsonResponse(w, http.StatusTooManyRequests, map[string]string{"error": "Too many open streams"})
+		return
+	}
+	if err != nil {
+		responses.JsonResponse(w, http.StatusServiceUnavailable, map[string]string{"error": "Stream unavailable"})
+		return
+	}
+	defer sub.Close()
+
+	// The server's write timeout is meant for regular requests.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if truncated {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, event := range replay {
+		writeEvent(w, event)
+	}
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(config.Get().Stream.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-sub.Done():
+			return
+		case event := <-sub.Events:
+			writeEvent(w, event)
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event stream.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// watchedPosts reads the watch query parameter.
+func watchedPosts(r *http.Request) ([]string, error) {
+	raw := r.URL.Query().Get("watch")
+	if raw == "" {
+		return nil, nil
+	}
+
+	ids := strings.Split(raw, ",")
+	if len(ids) > maxWatchedPosts {
+		return nil, fmt.Errorf("At most %d posts can be watched", maxWatchedPosts)
+	}
+
+	for i, id := range ids {
+		parsed, err := uuid.Parse(strings.TrimSpace(id))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid post ID in watch: %q", id)
+		}
+		ids[i] = parsed.String()
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"api/src/database"
+	"api/src/hashtags"
+	"api/src/models"
+	"api/src/repositories"
+	"api/src/responses"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+)
+
+const maxTagSuggestions = 20
+
+// Tags godoc
+// @Summary Get the posts with a hashtag
+// @Description Get posts tagged with a hashtag, newest first, with cursor pagination. Tags are matched case-insensitively and the leading '#' is optional
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param tag path string true "Hashtag"
+// @Param limit query int false "Number of posts to return (default 20, max 100)"
+// @Param cursor query string false "Cursor from the previous page's next_cursor"
+// @Success 200 {object} models.Page[models.Posts] "Page of posts"
+// @Failure 400 {object} map[string]string "Invalid tag or cursor"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /tags/{tag}/posts [get]
+func TagGetPosts(w http.ResponseWriter, r *http.Request) {
+	tag := hashtags.Normalize(mux.Vars(r)["tag"])
+	if tag == "" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid tag"})
+		return
+	}
+
+	limit, after, err := cursorPagination(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewPostsRepository(db)
+	posts, err := repository.FindTimeline(r.Context(), repositories.TimelineFilters{Tag: tag, After: after}, limit+1)
+	if err != nil {
+		log.Error().Err(err).Str("tag", tag).Msg("Failed to fetch tagged posts")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	if err := withViewerState(r, db, posts); err != nil {
+		log.Error().Err(err).Msg("Failed to load viewer state")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch posts"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, newPage[models.Posts](posts, limit, postPosition))
+}
+
+// Tags godoc
+// @Summary Autocomplete hashtags
+// @Description Suggest hashtags starting with the given prefix, most used first
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param q query string true "Prefix to complete, with or without the leading '#'"
+// @Param limit query int false "Number of suggestions (default 10, max 20)"
+// @Success 200 {array} models.Tag "Matching tags"
+// @Failure 400 {object} map[string]string "Invalid prefix"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /tags [get]
+func TagAutocomplete(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+
+	prefix := hashtags.Normalize(queryParams.Get("q"))
+	if prefix == "" {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid prefix"})
+		return
+	}
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > maxTagSuggestions {
+		limit = maxTagSuggestions
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewTagsRepository(db)
+	tags, err := repository.FindByPrefix(r.Context(), prefix, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to autocomplete tags")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch tags"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, tags)
+}
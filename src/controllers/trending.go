@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/database"
+	"api/src/repositories"
+	"api/src/responses"
+	"api/src/trending"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+)
+
+const maxTrendingLimit = 100
+
+// Trending godoc
+// @Summary Get trending hashtags
+// @Description Get the hashtags trending over a window, best first. Rankings are recomputed periodically, favouring recent posts and many distinct authors
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Param window query string false "One of the configured windows, such as 1h, 24h or 168h (default 24h)"
+// @Param limit query int false "Number of tags to return (default 10, max 100)"
+// @Success 200 {object} models.Trending "Trending tags"
+// @Failure 400 {object} map[string]string "Unknown window"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /trending/tags [get]
+func TrendingGetTags(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Get().Trending
+	queryParams := r.URL.Query()
+
+	window := queryParams.Get("window")
+	if window == "" {
+		window = cfg.DefaultWindow
+	}
+	if !slices.Contains(cfg.Windows, window) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Unknown window"})
+		return
+	}
+
+	limit, err := strconv.Atoi(queryParams.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+	if limit > maxTrendingLimit {
+		limit = maxTrendingLimit
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewTrendingRepository(db)
+	result, err := repository.FindByWindow(r.Context(), window, limit)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to fetch trending tags")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to fetch trending tags"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusOK, result)
+}
+
+// Trending godoc
+// @Summary Refresh trending hashtags
+// @Description Recompute the trending hashtags of every window now instead of waiting for the next scheduled run. Admins only
+// @Tags Tags
+// @Accept json
+// @Produce json
+// @Success 204 "No content"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /trending/tags/refresh [post]
+// @Security ApiKeyAuth
+func TrendingRefresh(w http.ResponseWriter, r *http.Request) {
+	if !auth.IsAdmin(r) {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "Forbidden"})
+		return
+	}
+
+	if err := trending.Refresh(r.Context(), config.Get().Trending); err != nil {
+		log.Error().Err(err).Msg("Failed to refresh trending tags")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to refresh trending tags"})
+		return
+	}
+
+	responses.JsonResponse(w, http.StatusNoContent, nil)
+}
@@ -1,22 +1,32 @@
 package controllers
 
 import (
+	"api/src/auth"
+	"api/src/config"
+	"api/src/controllers/dto"
 	"api/src/database"
+	"api/src/handles"
 	"api/src/models"
+	"api/src/notify"
 	"api/src/repositories"
 	"api/src/responses"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Users godoc
 // @Summary Create a new user
-// @Description Create a new user with name, email and password
+// @Description Create a new user with name, email and password, and optionally a handle and profile fields
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -45,6 +55,18 @@ func UserCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.Handle != nil {
+		if err := handles.Validate(*user.Handle); err != nil {
+			invalidHandle(w, err)
+			return
+		}
+	}
+
+	if !validWebsite(user.Website) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Website must be an http or https URL"})
+		return
+	}
+
 	db, err := database.Connect()
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
@@ -53,7 +75,7 @@ func UserCreate(w http.ResponseWriter, r *http.Request) {
 
 	repository := repositories.NewUsersRepository(db)
 
-	userExists, err := repository.FindByEmail(user.Email)
+	userExists, err := repository.FindByEmail(r.Context(), user.Email)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to check if user exists"})
 		return
@@ -64,7 +86,11 @@ func UserCreate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userId, err := repository.Create(user)
+	userId, err := repository.Create(r.Context(), user)
+	if errors.Is(err, repositories.ErrHandleTaken) {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Handle is taken"})
+		return
+	}
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
 		return
@@ -75,14 +101,14 @@ func UserCreate(w http.ResponseWriter, r *http.Request) {
 
 // Users godoc
 // @Summary Get all users
-// @Description Get all users with pagination and filtering
+// @Description Get all users with pagination and filtering. Emails are only shown for the authenticated user
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param limit query int false "Number of users to return (default 10)"
 // @Param page query int false "Page number (default 1)"
 // @Param name query string false "Filter by name"
-// @Param email query string false "Filter by email"
+// @Param handle query string false "Filter by handle"
 // @Success 200 {array} models.User "List of users"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /users [get]
@@ -109,8 +135,8 @@ func UserGetAll(w http.ResponseWriter, r *http.Request) {
 		filters["name"] = name
 	}
 
-	if email := queryParams.Get("email"); email != "" {
-		filters["email"] = email
+	if handle := queryParams.Get("handle"); handle != "" {
+		filters["handle"] = handles.Normalize(handle)
 	}
 
 	db, err := database.Connect()
@@ -120,18 +146,23 @@ func UserGetAll(w http.ResponseWriter, r *http.Request) {
 	}
 
 	repository := repositories.NewUsersRepository(db)
-	users, err := repository.FindMany(limit, page, filters)
+	users, err := repository.FindMany(r.Context(), limit, page, filters)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to retrieve users"})
 		return
 	}
 
+	viewerID, _ := auth.ExtractUserID(r)
+	for i := range users {
+		users[i].HideEmail(viewerID)
+	}
+
 	responses.JsonResponse(w, http.StatusOK, users)
 }
 
 // Users godoc
 // @Summary Get a user by ID
-// @Description Get the details of a specific user
+// @Description Get the details of a specific user. The email is only shown to the user themselves
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -152,7 +183,7 @@ func UserGetOne(w http.ResponseWriter, r *http.Request) {
 	}
 
 	repository := repositories.NewUsersRepository(db)
-	user, err := repository.FindById(id)
+	user, err := repository.FindById(r.Context(), id)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
 		return
@@ -163,12 +194,14 @@ func UserGetOne(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	viewerID, _ := auth.ExtractUserID(r)
+	user.HideEmail(viewerID)
 	responses.JsonResponse(w, http.StatusOK, user)
 }
 
 // Users godoc
 // @Summary Update a user
-// @Description Update a user with the provided fields
+// @Description Update your own name or password. Other fields have their own endpoints, such as /me/profile, /me/avatar, /me/time-zone and /me/message-settings
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -176,50 +209,127 @@ func UserGetOne(w http.ResponseWriter, r *http.Request) {
 // @Param request body map[string]interface{} true "Fields to update"
 // @Success 200 {object} models.User "Updated user"
 // @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /users/{id} [put]
 // @Security ApiKeyAuth
 func UserUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
 	params := mux.Vars(r)
 	id := params["id"]
 
+	if id != userID {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "You can only update your own account"})
+		return
+	}
+
 	var fields map[string]interface{}
 	if err := json.NewDecoder(r.Body).Decode(&fields); err != nil {
 		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
 		return
 	}
 
+	for key, value := range fields {
+		if text, ok := value.(string); !ok || text == "" {
+			responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("%s must be a non-empty string", key)})
+			return
+		}
+	}
+
 	db, err := database.Connect()
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
 		return
 	}
 
+	// Passwords are stored hashed, as on sign-in.
+	password, changesPassword := fields["password"].(string)
+	if changesPassword {
+		passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to generate password hash"})
+			return
+		}
+		fields["password"] = string(passwordHash)
+	}
+
 	repository := repositories.NewUsersRepository(db)
-	updatedUser, err := repository.Update(id, fields)
+	updatedUser, err := repository.Update(r.Context(), id, fields)
+	if errors.Is(err, repositories.ErrFieldNotAllowed) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Only name and password can be updated"})
+		return
+	}
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update user"})
 		return
 	}
 
+	if updatedUser != nil {
+		notifyUserUpdate(r, id, fields)
+		updatedUser.HideEmail(userID)
+	}
+
 	responses.JsonResponse(w, http.StatusOK, updatedUser)
 }
 
+// notifyUserUpdate tells the user their account changed, so they notice
+// changes they did not make.
+func notifyUserUpdate(r *http.Request, userID string, fields map[string]interface{}) {
+	var changed []string
+	for key := range fields {
+		if key != "password" {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+
+	if _, ok := fields["password"]; ok {
+		if _, err := notify.Publish(r.Context(), userID, notify.KindPasswordChanged, map[string]string{"ip": clientIP(r)}); err != nil {
+			log.Error().Err(err).Msg("Failed to publish password change notification")
+		}
+	}
+
+	if len(changed) > 0 {
+		if _, err := notify.Publish(r.Context(), userID, notify.KindProfileUpdated, map[string]interface{}{"fields": changed, "ip": clientIP(r)}); err != nil {
+			log.Error().Err(err).Msg("Failed to publish profile update notification")
+		}
+	}
+}
+
 // Users godoc
 // @Summary Delete a user
-// @Description Delete a user by ID
+// @Description Delete your own account, or any account as an admin
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID"
 // @Success 200 {object} map[string]string "Success message and deleted user ID"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 403 {object} map[string]string "Forbidden"
 // @Failure 500 {object} map[string]string "Internal server error"
 // @Router /users/{id} [delete]
 // @Security ApiKeyAuth
 func UserDelete(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
 	params := mux.Vars(r)
 	id := params["id"]
 
+	if id != userID && !auth.IsAdmin(r) {
+		responses.JsonResponse(w, http.StatusForbidden, map[string]string{"error": "You can only delete your own account"})
+		return
+	}
+
 	db, err := database.Connect()
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
@@ -227,11 +337,168 @@ func UserDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	repository := repositories.NewUsersRepository(db)
-	deletedUser, err := repository.Delete(id)
+	imageKeys, err := repository.FindImageKeys(r.Context(), id)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete the user"})
+		return
+	}
+
+	deletedUser, err := repository.Delete(r.Context(), id)
 	if err != nil {
 		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to delete the user"})
 		return
 	}
 
+	for _, key := range imageKeys {
+		deleteStoredImage(r, &key)
+	}
+
 	responses.JsonResponse(w, http.StatusOK, map[string]string{"message": "User deleted successfully", "user_id": deletedUser})
 }
+
+// Users godoc
+// @Summary Get a user by handle
+// @Description Get a user by handle, compared case-insensitively, with or without a leading '@'. A handle given up recently redirects to the user's current handle
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param handle path string true "Handle"
+// @Success 200 {object} models.User "User details"
+// @Success 302 {object} map[string]string "The handle changed, see Location"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /users/by-handle/{handle} [get]
+func UserGetByHandle(w http.ResponseWriter, r *http.Request) {
+	handle := handles.Normalize(mux.Vars(r)["handle"])
+	if handles.Validate(handle) != nil {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	repository := repositories.NewUsersRepository(db)
+	user, err := repository.FindByHandle(r.Context(), handle)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+		return
+	}
+
+	if user == nil {
+		current, err := repository.FindHandleRedirect(r.Context(), handle)
+		if err != nil {
+			responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to find user"})
+			return
+		}
+
+		if current == "" {
+			responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+			return
+		}
+
+		// Not permanent: the old handle is freed once the redirect expires.
+		w.Header().Set("Location", "/users/by-handle/"+url.PathEscape(current))
+		responses.JsonResponse(w, http.StatusFound, map[string]string{"handle": current})
+		return
+	}
+
+	viewerID, _ := auth.ExtractUserID(r)
+	user.HideEmail(viewerID)
+	responses.JsonResponse(w, http.StatusOK, user)
+}
+
+// Users godoc
+// @Summary Update your profile
+// @Description Change the authenticated user's handle, bio, location, website or pronouns. Only the fields given are changed. Handles are 3 to 30 letters, digits or underscores, with at least one letter, and are unique regardless of case. A replaced handle keeps redirecting to you, and cannot be taken by anyone else, for a grace period
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param request body dto.ProfileUpdateDTO true "Profile fields"
+// @Success 200 {object} models.User "Updated user"
+// @Failure 400 {object} map[string]string "Bad request"
+// @Failure 401 {object} map[string]string "Unauthorized"
+// @Failure 404 {object} map[string]string "User not found"
+// @Failure 409 {object} map[string]string "Handle is taken"
+// @Failure 500 {object} map[string]string "Internal server error"
+// @Router /me/profile [put]
+// @Security ApiKeyAuth
+func ProfileUpdate(w http.ResponseWriter, r *http.Request) {
+	userID, err := auth.ExtractUserID(r)
+	if err != nil {
+		responses.JsonResponse(w, http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+		return
+	}
+
+	var profileDTO dto.ProfileUpdateDTO
+	if !decodeBody(w, r, &profileDTO) {
+		return
+	}
+
+	if profileDTO.Handle != nil {
+		if err := handles.Validate(*profileDTO.Handle); err != nil {
+			invalidHandle(w, err)
+			return
+		}
+	}
+
+	if profileDTO.Website != nil && !validWebsite(*profileDTO.Website) {
+		responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": "Website must be an http or https URL"})
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to connect to database"})
+		return
+	}
+
+	profile := repositories.Profile{
+		Handle:   profileDTO.Handle,
+		Bio:      profileDTO.Bio,
+		Location: profileDTO.Location,
+		Website:  profileDTO.Website,
+		Pronouns: profileDTO.Pronouns,
+	}
+
+	found, err := repositories.NewUsersRepository(db).UpdateProfile(r.Context(), userID, profile, config.Get().Users.HandleRedirectTTL)
+	if errors.Is(err, repositories.ErrHandleTaken) {
+		responses.JsonResponse(w, http.StatusConflict, map[string]string{"error": "Handle is taken"})
+		return
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to update profile")
+		responses.JsonResponse(w, http.StatusInternalServerError, map[string]string{"error": "Failed to update profile"})
+		return
+	}
+
+	if !found {
+		responses.JsonResponse(w, http.StatusNotFound, map[string]string{"error": "User not found"})
+		return
+	}
+
+	respondWithUser(w, r, db, userID)
+}
+
+// invalidHandle explains why handles.Validate refused a handle.
+func invalidHandle(w http.ResponseWriter, err error) {
+	message := "Handles must be 3 to 30 letters, digits or underscores, with at least one letter"
+	if errors.Is(err, handles.ErrReserved) {
+		message = "Handle is reserved"
+	}
+	responses.JsonResponse(w, http.StatusBadRequest, map[string]string{"error": message})
+}
+
+// validWebsite reports whether website is empty or an absolute http or
+// https URL, so it is safe to render as a link.
+func validWebsite(website string) bool {
+	if website == "" {
+		return true
+	}
+
+	parsed, err := url.Parse(website)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
@@ -0,0 +1,48 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalid = errors.New("invalid cursor")
+
+// Position is a point in a list ordered by (created_at, id).
+type Position struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode returns an opaque cursor pointing just after p.
+func Encode(p Position) string {
+	raw := p.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + p.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// Decode parses a cursor produced by Encode.
+func Decode(s string) (Position, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Position{}, ErrInvalid
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return Position{}, ErrInvalid
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Position{}, ErrInvalid
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return Position{}, ErrInvalid
+	}
+
+	return Position{CreatedAt: t, ID: id}, nil
+}
@@ -0,0 +1,57 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Position
+	}{
+		{"utc", Position{CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC), ID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}},
+		{"nanoseconds", Position{CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 123456789, time.UTC), ID: "6ba7b811-9dad-11d1-80b4-00c04fd430c8"}},
+		{"other zone", Position{CreatedAt: time.Date(2024, 3, 1, 12, 30, 0, 0, time.FixedZone("", -5*3600)), ID: "6ba7b812-9dad-11d1-80b4-00c04fd430c8"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(Encode(tt.p))
+			if err != nil {
+				t.Fatalf("Decode(Encode(%v)) error = %v", tt.p, err)
+			}
+			if !got.CreatedAt.Equal(tt.p.CreatedAt) || got.ID != tt.p.ID {
+				t.Errorf("Decode(Encode(%v)) = %v", tt.p, got)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	encode := func(raw string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(raw))
+	}
+
+	tests := []struct {
+		name   string
+		cursor string
+	}{
+		{"empty", ""},
+		{"not base64", "not a cursor!"},
+		{"no separator", encode("2024-03-01T12:30:00Z")},
+		{"bad time", encode("yesterday|6ba7b810-9dad-11d1-80b4-00c04fd430c8")},
+		{"bad id", encode("2024-03-01T12:30:00Z|42")},
+		{"injected id", encode("2024-03-01T12:30:00Z|' OR 1=1 --")},
+		{"truncated", Encode(Position{CreatedAt: time.Now(), ID: "6ba7b810-9dad-11d1-80b4-00c04fd430c8"})[:20]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(tt.cursor); err != ErrInvalid {
+				t.Errorf("Decode(%q) error = %v, want ErrInvalid", tt.cursor, err)
+			}
+		})
+	}
+}
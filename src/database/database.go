@@ -1,32 +1,110 @@
 package database
 
 import (
+	"api/src/config"
 	"context"
+	"errors"
 	"fmt"
-	"log"
-	"os"
+	"sync"
 
-	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rs/zerolog/log"
 )
 
-func Connect() (*pgx.Conn, error) {
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s",
-		os.Getenv("DATABASE_USER"),
-		os.Getenv("DATABASE_PASSWORD"),
-		os.Getenv("DATABASE_HOST"),
-		os.Getenv("DATABASE_PORT"),
-		os.Getenv("DATABASE_NAME"))
+var (
+	pool *pgxpool.Pool
+	mu   sync.Mutex
+)
+
+// Open connects the shared pool at startup, retrying with exponential
+// backoff and jitter until the database answers or the configured connect
+// timeout elapses.
+func Open(ctx context.Context) error {
+	cfg := config.Get().Database
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+	defer cancel()
+
+	for attempt := 0; ; attempt++ {
+		_, err := Connect()
+		if err == nil {
+			return nil
+		}
+
+		delay := backoff(attempt, cfg.RetryBackoff, cfg.RetryMaxBackoff)
+		log.Warn().Err(err).Int("attempt", attempt+1).Dur("retry_in", delay).Msg("Database not available yet")
+
+		if sleep(ctx, delay) != nil {
+			return fmt.Errorf("database not available after %s: %w", cfg.ConnectTimeout, err)
+		}
+	}
+}
+
+// Connect returns the shared connection pool, opening it if needed. It
+// never exits the process, so it is safe to call from request handlers.
+func Connect() (*pgxpool.Pool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool != nil {
+		return pool, nil
+	}
 
-	conn, err := pgx.Connect(context.Background(), connStr)
+	cfg := config.Get().Database
+
+	poolConfig, err := pgxpool.ParseConfig(cfg.ConnString())
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Unable to connect to database: %v\n", err)
+		return nil, fmt.Errorf("invalid database configuration: %w", err)
 	}
+	poolConfig.MaxConns = int32(cfg.MaxConns)
+	poolConfig.ConnConfig.Tracer = &slowQueryTracer{threshold: cfg.SlowQueryThreshold}
 
-	err = conn.Ping(context.Background())
+	p, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
 	if err != nil {
-		log.Fatal("Failed to ping the database: ", err)
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
 	}
 
-	fmt.Println("Successfully connected to the database")
-	return conn, nil
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectAttemptTimeout)
+	defer cancel()
+
+	if err = p.Ping(ctx); err != nil {
+		p.Close()
+		return nil, fmt.Errorf("failed to ping the database: %w", err)
+	}
+
+	log.Info().Msg("Successfully connected to the database")
+	pool = p
+	return pool, nil
+}
+
+// Close closes the shared connection pool, waiting for acquired
+// connections to be released.
+func Close() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool != nil {
+		pool.Close()
+		pool = nil
+	}
+}
+
+// Ping checks that the shared pool can reach the database.
+func Ping(ctx context.Context) error {
+	p, err := connected()
+	if err != nil {
+		return err
+	}
+	return p.Ping(ctx)
+}
+
+// connected returns the shared pool without trying to open it.
+func connected() (*pgxpool.Pool, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if pool == nil {
+		return nil, errors.New("database is not connected")
+	}
+	return pool, nil
 }
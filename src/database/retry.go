@@ -0,0 +1,89 @@
+package database
+
+import (
+	"api/src/config"
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// IsTransient reports whether err is likely to go away if the same
+// statement is tried again: dropped or refused connections, serialization
+// failures and deadlocks. Context cancellation is never transient.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"57P01", // admin_shutdown
+			"57P03": // cannot_connect_now
+			return true
+		}
+		// Class 08 covers connection exceptions.
+		return len(pgErr.Code) == 5 && pgErr.Code[:2] == "08"
+	}
+
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Retry runs fn until it succeeds, returns a non-transient error, the
+// context ends or the configured number of attempts is used up. Only use
+// it for statements that are safe to run more than once.
+func Retry(ctx context.Context, fn func() error) error {
+	cfg := config.Get().Database
+
+	var err error
+	for attempt := 0; ; attempt++ {
+		err = fn()
+		if err == nil || !IsTransient(err) || attempt+1 >= cfg.QueryAttempts {
+			return err
+		}
+
+		if waitErr := sleep(ctx, backoff(attempt, cfg.RetryBackoff, cfg.RetryMaxBackoff)); waitErr != nil {
+			return err
+		}
+	}
+}
+
+// backoff returns an exponentially growing delay with full jitter, capped
+// at max.
+func backoff(attempt int, base, max time.Duration) time.Duration {
+	delay := base << attempt
+	if delay <= 0 || delay > max {
+		delay = max
+	}
+	return time.Duration(rand.Int63n(int64(delay) + 1))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// SchemaTables lists the tables created by scripts.sql. Keep it in sync
+// when adding tables so readiness can tell whether the schema is applied.
+var SchemaTables = []string{
+	"users",
+	"posts",
+	"followers",
+	"comments",
+	"reactions",
+	"reposts",
+	"bookmarks",
+	"tags",
+	"post_tags",
+	"trending_tags",
+	"mentions",
+	"notifications",
+	"recovery_email_verifications",
+	"account_recoveries",
+	"conversations",
+	"conversation_members",
+	"messages",
+	"media",
+	"handle_redirects",
+}
+
+// CheckSchema reports an error naming every table from SchemaTables that
+// does not exist in the connected database.
+func CheckSchema(ctx context.Context) error {
+	p, err := connected()
+	if err != nil {
+		return err
+	}
+
+	rows, err := p.Query(ctx, "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ANY($1)", SchemaTables)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	found := make(map[string]bool, len(SchemaTables))
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		found[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var missing []string
+	for _, table := range SchemaTables {
+		if !found[table] {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing tables: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
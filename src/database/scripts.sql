@@ -10,6 +10,17 @@ CREATE TABLE users (
     name VARCHAR(100) NOT NULL,
     email VARCHAR(100) NOT NULL unique,
     password VARCHAR(100) NOT NULL,
+    recovery_email VARCHAR(100) NULL,
+    recovery_email_verified_at TIMESTAMP NULL,
+    message_policy VARCHAR(16) NOT NULL DEFAULT 'everyone',
+    time_zone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+    avatar_key VARCHAR(255) NULL,
+    banner_key VARCHAR(255) NULL,
+    handle VARCHAR(30) NULL,
+    bio VARCHAR(160) NOT NULL DEFAULT '',
+    location VARCHAR(100) NOT NULL DEFAULT '',
+    website VARCHAR(255) NOT NULL DEFAULT '',
+    pronouns VARCHAR(40) NOT NULL DEFAULT '',
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NULL
 );
@@ -21,6 +32,7 @@ CREATE TABLE posts (
     title VARCHAR(100) NOT NULL,
     content TEXT NOT NULL,
     user_id UUID NOT NULL,
+    quoted_post_id UUID NULL,
     created_at TIMESTAMP NOT NULL DEFAULT NOW(),
     updated_at TIMESTAMP NULL
 );
@@ -28,4 +40,376 @@ CREATE TABLE posts (
 ALTER TABLE posts       
 ADD CONSTRAINT fk_posts_user_id
 FOREIGN KEY (user_id)
-REFERENCES users (id);
\ No newline at end of file
+REFERENCES users (id);
+
+DROP TABLE IF EXISTS followers;
+
+CREATE TABLE followers (
+    follower_id UUID NOT NULL,
+    followed_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (follower_id, followed_id),
+    CONSTRAINT chk_followers_not_self CHECK (follower_id <> followed_id)
+);
+
+ALTER TABLE followers
+ADD CONSTRAINT fk_followers_follower_id
+FOREIGN KEY (follower_id)
+REFERENCES users (id);
+
+ALTER TABLE followers
+ADD CONSTRAINT fk_followers_followed_id
+FOREIGN KEY (followed_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_followers_followed_id ON followers (followed_id, created_at DESC);
+
+CREATE INDEX idx_posts_created_at_id ON posts (created_at DESC, id DESC);
+
+CREATE INDEX idx_posts_user_id_created_at_id ON posts (user_id, created_at DESC, id DESC);
+
+-- Serves the per-month archive counts and listings.
+CREATE INDEX idx_posts_user_id_month ON posts (user_id, date_trunc('month', created_at));
+
+DROP TABLE IF EXISTS comments;
+
+CREATE TABLE comments (
+    id UUID PRIMARY KEY,
+    post_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    parent_id UUID NULL,
+    depth INT NOT NULL DEFAULT 0,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL
+);
+
+ALTER TABLE comments
+ADD CONSTRAINT fk_comments_post_id
+FOREIGN KEY (post_id)
+REFERENCES posts (id);
+
+ALTER TABLE comments
+ADD CONSTRAINT fk_comments_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+ALTER TABLE comments
+ADD CONSTRAINT fk_comments_parent_id
+FOREIGN KEY (parent_id)
+REFERENCES comments (id)
+ON DELETE CASCADE;
+
+CREATE INDEX idx_comments_post_id_created_at_id ON comments (post_id, created_at, id);
+
+CREATE INDEX idx_comments_parent_id ON comments (parent_id);
+
+DROP TABLE IF EXISTS reactions;
+
+CREATE TABLE reactions (
+    post_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (post_id, user_id, type)
+);
+
+ALTER TABLE reactions
+ADD CONSTRAINT fk_reactions_post_id
+FOREIGN KEY (post_id)
+REFERENCES posts (id);
+
+ALTER TABLE reactions
+ADD CONSTRAINT fk_reactions_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_reactions_post_id_type_created_at ON reactions (post_id, type, created_at DESC);
+
+CREATE INDEX idx_reactions_user_id ON reactions (user_id);
+
+-- Quote posts and reposts keep pointing at their original after it is
+-- deleted, so neither reference has a foreign key. Readers show a
+-- tombstone instead.
+CREATE INDEX idx_posts_quoted_post_id ON posts (quoted_post_id);
+
+DROP TABLE IF EXISTS reposts;
+
+CREATE TABLE reposts (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CONSTRAINT uq_reposts_user_id_post_id UNIQUE (user_id, post_id)
+);
+
+ALTER TABLE reposts
+ADD CONSTRAINT fk_reposts_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_reposts_user_id_created_at ON reposts (user_id, created_at DESC);
+
+DROP TABLE IF EXISTS bookmarks;
+
+CREATE TABLE bookmarks (
+    user_id UUID NOT NULL,
+    post_id UUID NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, post_id)
+);
+
+ALTER TABLE bookmarks
+ADD CONSTRAINT fk_bookmarks_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+ALTER TABLE bookmarks
+ADD CONSTRAINT fk_bookmarks_post_id
+FOREIGN KEY (post_id)
+REFERENCES posts (id);
+
+CREATE INDEX idx_bookmarks_user_id_created_at ON bookmarks (user_id, created_at DESC, post_id DESC);
+
+CREATE INDEX idx_bookmarks_post_id ON bookmarks (post_id);
+
+-- Tag names are stored case-folded, see the hashtags package.
+DROP TABLE IF EXISTS tags;
+
+CREATE TABLE tags (
+    id UUID PRIMARY KEY,
+    name VARCHAR(100) NOT NULL UNIQUE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_tags_name_pattern ON tags (name text_pattern_ops);
+
+DROP TABLE IF EXISTS post_tags;
+
+CREATE TABLE post_tags (
+    post_id UUID NOT NULL,
+    tag_id UUID NOT NULL,
+    PRIMARY KEY (post_id, tag_id)
+);
+
+ALTER TABLE post_tags
+ADD CONSTRAINT fk_post_tags_post_id
+FOREIGN KEY (post_id)
+REFERENCES posts (id);
+
+ALTER TABLE post_tags
+ADD CONSTRAINT fk_post_tags_tag_id
+FOREIGN KEY (tag_id)
+REFERENCES tags (id);
+
+CREATE INDEX idx_post_tags_tag_id ON post_tags (tag_id);
+
+-- Filled by the trending job, one set of rows per configured window.
+DROP TABLE IF EXISTS trending_tags;
+
+CREATE TABLE trending_tags (
+    window_name VARCHAR(32) NOT NULL,
+    tag_id UUID NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    posts_count INTEGER NOT NULL,
+    authors_count INTEGER NOT NULL,
+    computed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (window_name, tag_id)
+);
+
+ALTER TABLE trending_tags
+ADD CONSTRAINT fk_trending_tags_tag_id
+FOREIGN KEY (tag_id)
+REFERENCES tags (id);
+
+CREATE INDEX idx_trending_tags_window_name_score ON trending_tags (window_name, score DESC);
+
+-- Offsets count Unicode code points in posts.content.
+DROP TABLE IF EXISTS mentions;
+
+CREATE TABLE mentions (
+    post_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    start_offset INTEGER NOT NULL,
+    end_offset INTEGER NOT NULL,
+    PRIMARY KEY (post_id, start_offset)
+);
+
+ALTER TABLE mentions
+ADD CONSTRAINT fk_mentions_post_id
+FOREIGN KEY (post_id)
+REFERENCES posts (id);
+
+ALTER TABLE mentions
+ADD CONSTRAINT fk_mentions_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_mentions_user_id ON mentions (user_id);
+
+-- Handles are unique case-insensitively, see the handles package.
+CREATE UNIQUE INDEX uq_users_lower_handle ON users (LOWER(handle));
+
+DROP TABLE IF EXISTS notifications;
+
+CREATE TABLE notifications (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    kind VARCHAR(64) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    read_at TIMESTAMP NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE notifications
+ADD CONSTRAINT fk_notifications_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_notifications_user_id_created_at ON notifications (user_id, created_at DESC, id DESC);
+
+CREATE INDEX idx_notifications_user_id_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+-- Tokens are stored as SHA-256 hashes, see auth.NewOpaqueToken.
+DROP TABLE IF EXISTS recovery_email_verifications;
+
+CREATE TABLE recovery_email_verifications (
+    token_hash VARCHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE recovery_email_verifications
+ADD CONSTRAINT fk_recovery_email_verifications_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_recovery_email_verifications_user_id ON recovery_email_verifications (user_id);
+
+DROP TABLE IF EXISTS account_recoveries;
+
+CREATE TABLE account_recoveries (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL,
+    email VARCHAR(100) NOT NULL,
+    confirm_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    cancel_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    available_at TIMESTAMP NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NULL
+);
+
+ALTER TABLE account_recoveries
+ADD CONSTRAINT fk_account_recoveries_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE UNIQUE INDEX idx_account_recoveries_user_id_pending ON account_recoveries (user_id) WHERE status = 'pending';
+
+DROP TABLE IF EXISTS conversations;
+
+CREATE TABLE conversations (
+    id UUID PRIMARY KEY,
+    is_group BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_message_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+DROP TABLE IF EXISTS conversation_members;
+
+CREATE TABLE conversation_members (
+    conversation_id UUID NOT NULL,
+    user_id UUID NOT NULL,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    joined_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    last_read_at TIMESTAMP NULL,
+    left_at TIMESTAMP NULL,
+    PRIMARY KEY (conversation_id, user_id)
+);
+
+ALTER TABLE conversation_members
+ADD CONSTRAINT fk_conversation_members_conversation_id
+FOREIGN KEY (conversation_id)
+REFERENCES conversations (id);
+
+ALTER TABLE conversation_members
+ADD CONSTRAINT fk_conversation_members_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_conversation_members_user_id ON conversation_members (user_id) WHERE left_at IS NULL;
+
+DROP TABLE IF EXISTS messages;
+
+CREATE TABLE messages (
+    id UUID PRIMARY KEY,
+    conversation_id UUID NOT NULL,
+    sender_id UUID NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE messages
+ADD CONSTRAINT fk_messages_conversation_id
+FOREIGN KEY (conversation_id)
+REFERENCES conversations (id);
+
+ALTER TABLE messages
+ADD CONSTRAINT fk_messages_sender_id
+FOREIGN KEY (sender_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_messages_conversation_id_created_at ON messages (conversation_id, created_at DESC, id DESC);
+
+-- Uploads belong to their user until attached to a post. Unattached
+-- uploads, including those of deleted posts and users, are removed by the
+-- media garbage collector.
+DROP TABLE IF EXISTS media;
+
+CREATE TABLE media (
+    id UUID PRIMARY KEY,
+    user_id UUID NULL,
+    post_id UUID NULL,
+    position INT NOT NULL DEFAULT 0,
+    storage_key VARCHAR(255) NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    width INT NOT NULL,
+    height INT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE media
+ADD CONSTRAINT fk_media_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+ALTER TABLE media
+ADD CONSTRAINT fk_media_post_id
+FOREIGN KEY (post_id)
+REFERENCES posts (id);
+
+CREATE INDEX idx_media_post_id ON media (post_id, position);
+
+CREATE INDEX idx_media_unattached_created_at ON media (created_at) WHERE post_id IS NULL;
+
+-- Old handles, lowercased, keep pointing at their user for a while after
+-- a change. Nobody else may take them until expires_at.
+DROP TABLE IF EXISTS handle_redirects;
+
+CREATE TABLE handle_redirects (
+    handle VARCHAR(30) PRIMARY KEY,
+    user_id UUID NOT NULL,
+    expires_at TIMESTAMP NOT NULL
+);
+
+ALTER TABLE handle_redirects
+ADD CONSTRAINT fk_handle_redirects_user_id
+FOREIGN KEY (user_id)
+REFERENCES users (id);
+
+CREATE INDEX idx_handle_redirects_user_id ON handle_redirects (user_id);
@@ -0,0 +1,85 @@
+package database
+
+import (
+	"api/src/config"
+	"api/src/requestid"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+type traceKey struct{}
+
+type traceStart struct {
+	sql   string
+	args  []any
+	start time.Time
+}
+
+// slowQueryTracer logs every statement that takes longer than threshold,
+// together with the request that issued it.
+type slowQueryTracer struct {
+	threshold time.Duration
+}
+
+func (t *slowQueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	return context.WithValue(ctx, traceKey{}, traceStart{sql: data.SQL, args: data.Args, start: time.Now()})
+}
+
+func (t *slowQueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	started, ok := ctx.Value(traceKey{}).(traceStart)
+	if !ok {
+		return
+	}
+
+	duration := time.Since(started.start)
+	if duration < t.threshold {
+		return
+	}
+
+	event := log.Warn().
+		Str("sql", started.sql).
+		Strs("args", redactArgs(started.args)).
+		Dur("duration", duration).
+		Str("request_id", requestid.FromContext(ctx))
+	if data.Err != nil {
+		event = event.Err(data.Err)
+	}
+	event.Msg("Slow query")
+}
+
+// redactArgs keeps numbers, booleans and times, which help explain a plan,
+// and hides anything that may carry user data.
+func redactArgs(args []any) []string {
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		switch v := arg.(type) {
+		case nil:
+			redacted[i] = "NULL"
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64, bool:
+			redacted[i] = fmt.Sprint(v)
+		case time.Time:
+			redacted[i] = v.Format(time.RFC3339)
+		case string:
+			redacted[i] = fmt.Sprintf("<string len=%d>", len(v))
+		case []byte:
+			redacted[i] = fmt.Sprintf("<bytes len=%d>", len(v))
+		default:
+			redacted[i] = fmt.Sprintf("<%T>", v)
+		}
+	}
+	return redacted
+}
+
+// WithTimeout bounds ctx by the configured statement timeout unless it
+// already has an earlier deadline.
+func WithTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	timeout := config.Get().Database.StatementTimeout
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= timeout {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,128 @@
+// Package events is the internal bus announcing changes to users and
+// posts. With the Postgres bus, subscribers on every replica receive the
+// changes made on all of them.
+package events
+
+import (
+	"api/src/config"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// Event types.
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+	PostCreated = "post.created"
+	PostUpdated = "post.updated"
+	PostDeleted = "post.deleted"
+)
+
+// MaxDataSize bounds the encoded data an event carries. Postgres rejects
+// notifications over 8000 bytes, so larger data is left out and
+// subscribers load the entity by ID instead.
+const MaxDataSize = 6000
+
+// instance identifies this process, so the Postgres bus can skip its own
+// notifications.
+var instance = uuid.NewString()
+
+// Event is the envelope published on the bus.
+type Event struct {
+	Type string `json:"type"`
+	// ID is the changed user or post.
+	ID string `json:"id"`
+	// UserID owns the change: the user itself, or the author of a post.
+	UserID string `json:"user_id,omitempty"`
+	// Data is the entity after the change. It is nil when it did not fit
+	// in MaxDataSize.
+	Data   json.RawMessage `json:"data,omitempty"`
+	Origin string          `json:"origin"`
+}
+
+// New builds an event, leaving data out when it is too large to publish.
+func New(eventType string, id string, userID string, data any) Event {
+	event := Event{Type: eventType, ID: id, UserID: userID, Origin: instance}
+	if data == nil {
+		return event
+	}
+
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		log.Warn().Err(err).Str("type", eventType).Msg("Failed to encode event data")
+		return event
+	}
+	if len(encoded) <= MaxDataSize {
+		event.Data = encoded
+	}
+	return event
+}
+
+// Handler receives events on the publisher's or listener's goroutine, so
+// it should return quickly.
+type Handler func(Event)
+
+// Bus delivers published events to every subscriber.
+type Bus interface {
+	Publish(ctx context.Context, event Event) error
+	Subscribe(handler Handler) (unsubscribe func())
+}
+
+// Open builds the bus selected by cfg.Bus.
+func Open(cfg config.EventsConfig) (Bus, error) {
+	switch cfg.Bus {
+	case "local":
+		return NewLocal(), nil
+	case "postgres":
+		return NewPostgres(cfg.Channel)
+	default:
+		return nil, fmt.Errorf("unknown event bus %q", cfg.Bus)
+	}
+}
+
+var (
+	current Bus = NewLocal()
+	mu      sync.RWMutex
+)
+
+// Configure makes bus the one used by Publish and Subscribe. Until it is
+// called, events only reach subscribers in this process.
+func Configure(bus Bus) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = bus
+}
+
+func get() Bus {
+	mu.RLock()
+	defer mu.RUnlock()
+	return current
+}
+
+// Publish publishes event on the configured bus, even if ctx is canceled.
+// Failures are only logged because the change it announces has already
+// been made.
+func Publish(ctx context.Context, event Event) {
+	if err := get().Publish(context.WithoutCancel(ctx), event); err != nil {
+		log.Error().Err(err).Str("type", event.Type).Str("id", event.ID).Msg("Failed to publish event")
+	}
+}
+
+// Subscribe registers handler on the configured bus.
+func Subscribe(handler Handler) (unsubscribe func()) {
+	return get().Subscribe(handler)
+}
+
+// Listen receives events from other replicas until ctx is done, when the
+// configured bus needs to.
+func Listen(ctx context.Context) {
+	if listener, ok := get().(interface{ Listen(context.Context) }); ok {
+		listener.Listen(ctx)
+	}
+}
@@ -0,0 +1,50 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+// Local delivers events to subscribers in this process only.
+type Local struct {
+	mu       sync.RWMutex
+	handlers map[int]Handler
+	next     int
+}
+
+func NewLocal() *Local {
+	return &Local{handlers: make(map[int]Handler)}
+}
+
+func (l *Local) Publish(ctx context.Context, event Event) error {
+	l.deliver(event)
+	return nil
+}
+
+func (l *Local) Subscribe(handler Handler) (unsubscribe func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	id := l.next
+	l.next++
+	l.handlers[id] = handler
+
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		delete(l.handlers, id)
+	}
+}
+
+func (l *Local) deliver(event Event) {
+	l.mu.RLock()
+	handlers := make([]Handler, 0, len(l.handlers))
+	for _, handler := range l.handlers {
+		handlers = append(handlers, handler)
+	}
+	l.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
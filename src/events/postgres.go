@@ -0,0 +1,116 @@
+package events
+
+import (
+	"api/src/database"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	listenBackoff    = time.Second
+	listenMaxBackoff = 30 * time.Second
+)
+
+// Postgres publishes events with NOTIFY on a channel every replica
+// LISTENs to. Events are delivered to local subscribers right away and
+// to other replicas once they receive the notification. Events sent
+// while a replica's listener is reconnecting are lost to it.
+type Postgres struct {
+	local   *Local
+	channel string
+}
+
+func NewPostgres(channel string) (*Postgres, error) {
+	if _, err := database.Connect(); err != nil {
+		return nil, err
+	}
+	return &Postgres{local: NewLocal(), channel: channel}, nil
+}
+
+func (p *Postgres) Publish(ctx context.Context, event Event) error {
+	p.local.deliver(event)
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err = db.Exec(ctx, "SELECT pg_notify($1, $2)", p.channel, string(payload))
+	return err
+}
+
+func (p *Postgres) Subscribe(handler Handler) (unsubscribe func()) {
+	return p.local.Subscribe(handler)
+}
+
+// Listen delivers the events published by other replicas until ctx is
+// done, reconnecting with backoff when the connection is lost.
+func (p *Postgres) Listen(ctx context.Context) {
+	delay := listenBackoff
+	for {
+		err := p.listen(ctx, func() { delay = listenBackoff })
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error().Err(err).Dur("retry_in", delay).Msg("Lost the event bus connection")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = min(2*delay, listenMaxBackoff)
+	}
+}
+
+// listen holds a dedicated connection LISTENing to the channel. It calls
+// connected once listening.
+func (p *Postgres) listen(ctx context.Context, connected func()) error {
+	db, err := database.Connect()
+	if err != nil {
+		return err
+	}
+
+	pooled, err := db.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// Take the connection out of the pool: it must not be handed to
+	// queries while listening.
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{p.channel}.Sanitize()); err != nil {
+		return err
+	}
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var event Event
+		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
+			log.Warn().Err(err).Msg("Ignoring malformed event")
+			continue
+		}
+		if event.Origin == instance {
+			continue
+		}
+		p.local.deliver(event)
+	}
+}
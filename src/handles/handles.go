@@ -0,0 +1,72 @@
+// Package handles checks user handles, the unique names users can be
+// found by, as in /users/by-handle/{handle}.
+package handles
+
+import (
+	"errors"
+	"strings"
+)
+
+// Length limits of a handle, in characters.
+const (
+	MinLength = 3
+	MaxLength = 30
+)
+
+var (
+	// ErrInvalid is returned for handles that break the format rules.
+	ErrInvalid = errors.New("handles must be 3 to 30 letters, digits or underscores, with at least one letter")
+	// ErrReserved is returned for handles kept for the service itself.
+	ErrReserved = errors.New("handle is reserved")
+)
+
+// reserved handles could be mistaken for the service, its staff or its
+// routes. They are matched case-insensitively, ignoring underscores.
+var reserved = map[string]bool{
+	"about": true, "account": true, "admin": true, "administrator": true,
+	"all": true, "anonymous": true, "api": true, "auth": true,
+	"avatars": true, "banners": true, "bookmarks": true, "byhandle": true,
+	"conversations": true, "devbook": true, "everyone": true, "health": true,
+	"help": true, "here": true, "home": true, "login": true,
+	"logout": true, "me": true, "media": true, "mentions": true,
+	"moderator": true, "new": true, "notifications": true, "null": true,
+	"official": true, "posts": true, "privacy": true, "register": true,
+	"root": true, "security": true, "settings": true, "signin": true,
+	"signup": true, "staff": true, "support": true, "swagger": true,
+	"system": true, "tags": true, "terms": true, "trending": true,
+	"undefined": true, "user": true, "users": true, "ws": true,
+}
+
+// Normalize drops a leading '@' and lowercases handle, giving the form
+// handles are compared in.
+func Normalize(handle string) string {
+	return strings.ToLower(strings.TrimPrefix(handle, "@"))
+}
+
+// Validate reports why handle cannot be used, or nil if it can. Handles
+// are ASCII so that look-alike letters from other scripts cannot be used
+// to impersonate someone.
+func Validate(handle string) error {
+	if len(handle) < MinLength || len(handle) > MaxLength {
+		return ErrInvalid
+	}
+
+	hasLetter := false
+	for _, r := range handle {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			hasLetter = true
+		case r >= '0' && r <= '9', r == '_':
+		default:
+			return ErrInvalid
+		}
+	}
+	if !hasLetter {
+		return ErrInvalid
+	}
+
+	if reserved[strings.ReplaceAll(Normalize(handle), "_", "")] {
+		return ErrReserved
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package handles
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   error
+	}{
+		{"bob", nil},
+		{"Bob_Smith", nil},
+		{"r2d2", nil},
+		{"_x_", nil},
+		{strings.Repeat("a", MaxLength), nil},
+		{"ab", ErrInvalid},
+		{strings.Repeat("a", MaxLength+1), ErrInvalid},
+		{"", ErrInvalid},
+		{"123", ErrInvalid},
+		{"___", ErrInvalid},
+		{"bob.smith", ErrInvalid},
+		{"bob-smith", ErrInvalid},
+		{"bob smith", ErrInvalid},
+		{"@bob", ErrInvalid},
+		{"josé", ErrInvalid},
+		{"\u0430dmin", ErrInvalid}, // Cyrillic a
+		{"ｂｏｂ", ErrInvalid},
+		{"admin", ErrReserved},
+		{"ADMIN", ErrReserved},
+		{"Ad_min", ErrReserved},
+		{"by_handle", ErrReserved},
+		{"_me_", ErrReserved},
+		{"admins", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Validate(tt.handle); got != tt.want {
+			t.Errorf("Validate(%q) = %v, want %v", tt.handle, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		handle string
+		want   string
+	}{
+		{"bob", "bob"},
+		{"@Bob_Smith", "bob_smith"},
+		{"BOB", "bob"},
+		{"@@bob", "@bob"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.handle); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.handle, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package hashtags finds the hashtags in post content.
+package hashtags
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// MaxLength is the longest tag kept, in characters, not counting the '#'.
+const MaxLength = 100
+
+// Extract returns the distinct normalized hashtags in content, in the
+// order they first appear.
+//
+// A hashtag is a '#' followed by letters, marks, digits and underscores,
+// in any script. It must contain at least one letter, so "#1" is not a
+// tag, and the '#' must not follow another tag character, so URL
+// fragments such as "page#section" are skipped.
+func Extract(content string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+
+	var prev rune
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r != '#' || isTagRune(prev) {
+			prev = r
+			i += size
+			continue
+		}
+
+		start := i + size
+		end := start
+		for end < len(content) {
+			next, nextSize := utf8.DecodeRuneInString(content[end:])
+			if !isTagRune(next) {
+				break
+			}
+			end += nextSize
+		}
+
+		if tag := Normalize(content[start:end]); tag != "" && !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+
+		prev = r
+		if end > start {
+			prev, _ = utf8.DecodeLastRuneInString(content[start:end])
+		}
+		i = end
+	}
+
+	return tags
+}
+
+// Normalize case-folds a tag so that differently written forms of the
+// same tag compare equal. A leading '#' is dropped. It returns "" when
+// the input is not a valid tag.
+func Normalize(tag string) string {
+	tag = strings.TrimPrefix(tag, "#")
+	tag = cases.Fold().String(norm.NFKC.String(tag))
+	tag = norm.NFC.String(tag)
+
+	hasLetter := false
+	length := 0
+	for _, r := range tag {
+		if !isTagRune(r) {
+			return ""
+		}
+		if unicode.IsLetter(r) {
+			hasLetter = true
+		}
+		length++
+	}
+
+	if !hasLetter || length > MaxLength {
+		return ""
+	}
+
+	return tag
+}
+
+func isTagRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsNumber(r)
+}
@@ -0,0 +1,62 @@
+package hashtags
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"none", "no tags here", nil},
+		{"single", "hello #golang", []string{"golang"}},
+		{"case folded and deduplicated", "#Go #GO #go", []string{"go"}},
+		{"first appearance order", "#b #a #b", []string{"b", "a"}},
+		{"punctuation ends tag", "#go, #rust! (#zig)", []string{"go", "rust", "zig"}},
+		{"underscore and digits", "#go_1_23", []string{"go_1_23"}},
+		{"digits only", "#1 #2024", nil},
+		{"url fragment", "see page#section", nil},
+		{"double hash", "##go", []string{"go"}},
+		{"bare hash", "# go #", nil},
+		{"unicode letters", "#café #日本語", []string{"café", "日本語"}},
+		{"combining mark normalized", "#cafe\u0301", []string{"café"}},
+		{"full width folded", "#ＧＯ", []string{"go"}},
+		{"emoji ends tag", "#go🚀", []string{"go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"#Go", "go"},
+		{"go", "go"},
+		{"Straße", "strasse"},
+		{"", ""},
+		{"#", ""},
+		{"123", ""},
+		{"go-lang", ""},
+		{"go lang", ""},
+		{strings.Repeat("a", MaxLength), strings.Repeat("a", MaxLength)},
+		{strings.Repeat("a", MaxLength+1), ""},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.tag); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusUp       = "UP"
+	StatusDown     = "DOWN"
+	StatusDraining = "DRAINING"
+)
+
+// Checker reports whether a dependency is usable.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc adapts a function to the Checker interface.
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status    string            `json:"status"`
+	Timestamp time.Time         `json:"timestamp"`
+	Checks    map[string]Result `json:"checks,omitempty"`
+}
+
+type entry struct {
+	name    string
+	checker Checker
+
+	mu     sync.Mutex
+	result *Result
+}
+
+var (
+	draining atomic.Bool
+
+	registryMu sync.RWMutex
+	registry   []*entry
+
+	checkTimeout = 2 * time.Second
+	cacheTTL     = 2 * time.Second
+)
+
+// SetDraining marks the instance as shutting down so readiness checks
+// start failing before the server stops accepting connections.
+func SetDraining() {
+	draining.Store(true)
+}
+
+// IsDraining reports whether the instance is shutting down.
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// Configure sets how long each check may run and how long its result is
+// reused before the check runs again.
+func Configure(timeout, ttl time.Duration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	checkTimeout = timeout
+	cacheTTL = ttl
+}
+
+// Register adds a named dependency to the readiness report. Registering a
+// name twice replaces the earlier checker.
+func Register(name string, checker Checker) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	for i, e := range registry {
+		if e.name == name {
+			registry[i] = &entry{name: name, checker: checker}
+			return
+		}
+	}
+	registry = append(registry, &entry{name: name, checker: checker})
+}
+
+// Ready runs every registered check concurrently, reusing results younger
+// than the cache TTL, and aggregates them. The instance is ready only when
+// it is not draining and every check passes.
+func Ready(ctx context.Context) Report {
+	registryMu.RLock()
+	entries := append([]*entry(nil), registry...)
+	timeout, ttl := checkTimeout, cacheTTL
+	registryMu.RUnlock()
+
+	report := Report{
+		Status:    StatusUp,
+		Timestamp: time.Now(),
+		Checks:    make(map[string]Result, len(entries)),
+	}
+
+	results := make([]Result, len(entries))
+	var wg sync.WaitGroup
+	for i, e := range entries {
+		wg.Add(1)
+		go func(i int, e *entry) {
+			defer wg.Done()
+			results[i] = e.run(ctx, timeout, ttl)
+		}(i, e)
+	}
+	wg.Wait()
+
+	for i, e := range entries {
+		report.Checks[e.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+
+	if IsDraining() {
+		report.Status = StatusDraining
+	}
+
+	return report
+}
+
+func (e *entry) run(ctx context.Context, timeout, ttl time.Duration) Result {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.result != nil && time.Since(e.result.CheckedAt) < ttl {
+		return *e.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := e.checker.Check(ctx)
+
+	result := Result{
+		Status:    StatusUp,
+		Duration:  time.Since(start).String(),
+		CheckedAt: start,
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	e.result = &result
+	return result
+}
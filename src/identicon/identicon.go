@@ -0,0 +1,105 @@
+// Package identicon draws the default avatar of users who have not
+// uploaded one: a symmetric pattern derived from a seed, such as the
+// user ID, so the same user always gets the same picture.
+package identicon
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const (
+	cells  = 5
+	cell   = 60
+	margin = 30
+	// Size is the width and height of identicons, in pixels.
+	Size = cells*cell + 2*margin
+)
+
+var background = color.RGBA{0xf0, 0xf0, 0xf0, 0xff}
+
+// PNG draws the identicon of seed.
+func PNG(seed string) ([]byte, error) {
+	sum := sha256.Sum256([]byte(seed))
+
+	img := image.NewPaletted(image.Rect(0, 0, Size, Size), color.Palette{background, foreground(sum)})
+
+	// Only the left half and the middle column come from the hash; the
+	// right half mirrors them.
+	bit := 0
+	for column := 0; column < (cells+1)/2; column++ {
+		for row := 0; row < cells; row++ {
+			on := sum[4+bit/8]&(1<<(bit%8)) != 0
+			bit++
+			if !on {
+				continue
+			}
+			fill(img, column, row)
+			fill(img, cells-1-column, row)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// foreground picks a saturated, mid-light colour from the hash so the
+// pattern always stands out from the background.
+func foreground(sum [sha256.Size]byte) color.Color {
+	hue := float64(uint16(sum[0])<<8|uint16(sum[1])) / 65536 * 360
+	return hsl(hue, 0.45+float64(sum[2])/255*0.2, 0.45+float64(sum[3])/255*0.15)
+}
+
+func fill(img *image.Paletted, column, row int) {
+	x0, y0 := margin+column*cell, margin+row*cell
+	for y := y0; y < y0+cell; y++ {
+		for x := x0; x < x0+cell; x++ {
+			img.SetColorIndex(x, y, 1)
+		}
+	}
+}
+
+func hsl(h, s, l float64) color.RGBA {
+	c := (1 - abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - abs(mod2(hp)-1))
+
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g = c, x
+	case hp < 2:
+		r, g = x, c
+	case hp < 3:
+		g, b = c, x
+	case hp < 4:
+		g, b = x, c
+	case hp < 5:
+		r, b = x, c
+	default:
+		r, b = c, x
+	}
+
+	m := l - c/2
+	return color.RGBA{uint8((r + m) * 255), uint8((g + m) * 255), uint8((b + m) * 255), 0xff}
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func mod2(v float64) float64 {
+	for v >= 2 {
+		v -= 2
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+// Package live forwards post events from the event bus to the clients of
+// the SSE stream and the WebSocket gateway.
+package live
+
+import (
+	"api/src/database"
+	"api/src/events"
+	"api/src/repositories"
+	"api/src/socket"
+	"api/src/stream"
+	"context"
+	"encoding/json"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Start subscribes to the configured event bus.
+func Start() (stop func()) {
+	return events.Subscribe(forward)
+}
+
+// forward sends a post event to the stream and to the WebSocket topics of
+// the post and its author. The stream and gateway use the bus's event
+// types.
+func forward(event events.Event) {
+	switch event.Type {
+	case events.PostCreated, events.PostUpdated, events.PostDeleted:
+	default:
+		return
+	}
+
+	data, err := postData(event)
+	if err != nil {
+		log.Error().Err(err).Str("post_id", event.ID).Msg("Failed to load post for live clients")
+		return
+	}
+	if data == nil {
+		return
+	}
+
+	if err := stream.Publish(event.Type, event.ID, data); err != nil {
+		log.Error().Err(err).Msg("Failed to publish post to the stream")
+	}
+
+	topics := []string{socket.PostTopic(event.ID), socket.UserPostsTopic(event.UserID)}
+	if event.Type == events.PostCreated {
+		topics = append(topics, socket.TopicNewPosts)
+	}
+	for _, topic := range topics {
+		if err := socket.Publish(topic, event.Type, data); err != nil {
+			log.Error().Err(err).Str("topic", topic).Msg("Failed to publish post to WebSocket topic")
+		}
+	}
+}
+
+// postData returns the event's data, loading the post when it was too
+// large to travel with the event. It returns nil when the post is gone.
+func postData(event events.Event) (json.RawMessage, error) {
+	if event.Data != nil {
+		return event.Data, nil
+	}
+	if event.Type == events.PostDeleted {
+		return json.Marshal(map[string]string{"id": event.ID})
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	post, err := repositories.NewPostsRepository(db).FindById(context.Background(), event.ID)
+	if err != nil || post == nil {
+		return nil, err
+	}
+	return json.Marshal(post)
+}
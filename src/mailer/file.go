@@ -0,0 +1,46 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// File writes each message to its own .eml file in a directory instead of
+// sending it.
+type File struct {
+	from string
+	dir  string
+}
+
+// NewFile creates dir if needed and returns a mailer writing to it.
+func NewFile(from string, dir string) (*File, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &File{from: from, dir: dir}, nil
+}
+
+func (f *File) Send(ctx context.Context, msg Message) error {
+	data, err := format(f.from, msg)
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), uuid.NewString())
+	return os.WriteFile(filepath.Join(f.dir, name), data, 0o600)
+}
+
+// Check makes sure the directory is still writable.
+func (f *File) Check(ctx context.Context) error {
+	probe, err := os.CreateTemp(f.dir, ".check-*")
+	if err != nil {
+		return err
+	}
+	probe.Close()
+	return os.Remove(probe.Name())
+}
@@ -0,0 +1,113 @@
+// Package mailer sends emails through a configurable transport.
+package mailer
+
+import (
+	"api/src/config"
+	"bytes"
+	"context"
+	"fmt"
+	"mime"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is a plain text email.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. Check reports whether the transport is
+// usable, for readiness.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+	Check(ctx context.Context) error
+}
+
+var (
+	current Mailer
+	mu      sync.RWMutex
+)
+
+// New builds the mailer selected by cfg.Transport.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Transport {
+	case "smtp":
+		return NewSMTP(cfg), nil
+	case "file":
+		return NewFile(cfg.From, cfg.Dir)
+	default:
+		return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+	}
+}
+
+// Configure makes m the mailer used by Send and Check.
+func Configure(m Mailer) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = m
+}
+
+func get() (Mailer, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if current == nil {
+		return nil, fmt.Errorf("mailer is not configured")
+	}
+	return current, nil
+}
+
+// Send delivers msg with the configured mailer.
+func Send(ctx context.Context, msg Message) error {
+	m, err := get()
+	if err != nil {
+		return err
+	}
+	return m.Send(ctx, msg)
+}
+
+// Check reports whether the configured mailer can send.
+func Check(ctx context.Context) error {
+	m, err := get()
+	if err != nil {
+		return err
+	}
+	return m.Check(ctx)
+}
+
+// format renders msg as an RFC 5322 message with CRLF line endings.
+func format(from string, msg Message) ([]byte, error) {
+	sender, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid sender: %w", err)
+	}
+	recipient, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipient: %w", err)
+	}
+
+	domain := "localhost"
+	if _, host, ok := strings.Cut(sender.Address, "@"); ok {
+		domain = host
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", sender.String())
+	fmt.Fprintf(&b, "To: %s\r\n", recipient.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: <%s@%s>\r\n", uuid.NewString(), domain)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+
+	return b.Bytes(), nil
+}
@@ -0,0 +1,117 @@
+package mailer
+
+import (
+	"api/src/config"
+	"context"
+	"crypto/tls"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// defaultTimeout bounds a whole SMTP exchange when ctx has no deadline.
+const defaultTimeout = 30 * time.Second
+
+// SMTP sends messages through an SMTP server, upgrading the connection
+// with STARTTLS when the server offers it.
+type SMTP struct {
+	from     string
+	host     string
+	addr     string
+	user     string
+	password string
+}
+
+func NewSMTP(cfg config.MailConfig) *SMTP {
+	return &SMTP{
+		from:     cfg.From,
+		host:     cfg.SMTPHost,
+		addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		user:     cfg.SMTPUser,
+		password: cfg.SMTPPassword,
+	}
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	data, err := format(s.from, msg)
+	if err != nil {
+		return err
+	}
+	sender, _ := mail.ParseAddress(s.from)
+	recipient, _ := mail.ParseAddress(msg.To)
+
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if s.user != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.user, s.password, s.host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(sender.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(recipient.Address); err != nil {
+		return err
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// Check connects to the server and says hello without sending anything.
+func (s *SMTP) Check(ctx context.Context) error {
+	client, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Noop(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+func (s *SMTP) dial(ctx context.Context) (*smtp.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(defaultTimeout)
+	}
+	conn.SetDeadline(deadline)
+
+	client, err := smtp.NewClient(conn, s.host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: s.host}); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
@@ -0,0 +1,70 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"golang.org/x/image/draw"
+)
+
+// maxPixels bounds the images Crop decodes, since a small file can
+// describe a huge image.
+const maxPixels = 40_000_000
+
+// ErrTooManyPixels is returned by Crop for images too large to decode.
+var ErrTooManyPixels = errors.New("image has too many pixels")
+
+// Fixed output sizes of profile images.
+const (
+	AvatarSize   = 400
+	BannerWidth  = 1500
+	BannerHeight = 500
+)
+
+// Crop cuts the largest centred region of img with the aspect ratio of
+// width by height and scales it to exactly that size. The result is
+// re-encoded, so no metadata survives: JPEG for opaque images and PNG for
+// the rest. Only the first frame of animated GIFs is kept.
+func Crop(img *Image, width, height int) (*Image, error) {
+	if img.Width*img.Height > maxPixels {
+		return nil, ErrTooManyPixels
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(img.Data))
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	bounds := src.Bounds()
+	region := bounds
+	if bounds.Dx()*height > bounds.Dy()*width {
+		w := max(bounds.Dy()*width/height, 1)
+		region.Min.X += (bounds.Dx() - w) / 2
+		region.Max.X = region.Min.X + w
+	} else {
+		h := max(bounds.Dx()*height/width, 1)
+		region.Min.Y += (bounds.Dy() - h) / 2
+		region.Max.Y = region.Min.Y + h
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, region, draw.Src, nil)
+
+	var buf bytes.Buffer
+	cropped := &Image{Width: width, Height: height}
+	if dst.Opaque() {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 88})
+		cropped.MimeType, cropped.Extension = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&buf, dst)
+		cropped.MimeType, cropped.Extension = "image/png", ".png"
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cropped.Data = buf.Bytes()
+	return cropped, nil
+}
@@ -0,0 +1,77 @@
+package media
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"api/src/repositories"
+	"api/src/storage"
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const gcBatchSize = 100
+
+// Collect deletes the uploads left unattached for longer than
+// cfg.UnattachedTTL and returns how many were deleted.
+func Collect(ctx context.Context, cfg config.MediaConfig) (int, error) {
+	db, err := database.Connect()
+	if err != nil {
+		return 0, err
+	}
+
+	store, err := storage.Get()
+	if err != nil {
+		return 0, err
+	}
+
+	repository := repositories.NewMediaRepository(db)
+	deleted := 0
+	for {
+		keys, err := repository.DeleteUnattached(ctx, cfg.UnattachedTTL, gcBatchSize)
+		if err != nil {
+			return deleted, err
+		}
+
+		// The rows are gone, so a file that fails to delete is only
+		// logged: nothing refers to it anymore.
+		for _, key := range keys {
+			if err := store.Delete(ctx, key); err != nil {
+				log.Error().Err(err).Str("key", key).Msg("Failed to delete unattached media")
+			}
+		}
+
+		deleted += len(keys)
+		if len(keys) < gcBatchSize {
+			return deleted, nil
+		}
+	}
+}
+
+// Run collects unattached uploads every cfg.GCInterval until ctx is done.
+// A zero interval disables it.
+func Run(ctx context.Context, cfg config.MediaConfig) {
+	if cfg.GCInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.GCInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		deleted, err := Collect(ctx, cfg)
+		if err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to collect unattached media")
+		}
+		if deleted > 0 {
+			log.Info().Int("deleted", deleted).Msg("Collected unattached media")
+		}
+	}
+}
@@ -0,0 +1,69 @@
+// Package media validates uploaded images and removes the metadata they
+// carry, such as EXIF location and camera details.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/gabriel-vasile/mimetype"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	// ErrUnsupportedType is returned for content that is not one of the
+	// supported image types, whatever its name or declared type says.
+	ErrUnsupportedType = errors.New("unsupported media type")
+	// ErrMalformed is returned for images that cannot be parsed.
+	ErrMalformed = errors.New("malformed image")
+)
+
+// strippers remove metadata from each supported type, detected from the
+// content itself.
+var strippers = map[string]func([]byte) ([]byte, error){
+	"image/jpeg": stripJPEG,
+	"image/png":  stripPNG,
+	"image/webp": stripWebP,
+	"image/gif":  func(data []byte) ([]byte, error) { return data, nil },
+}
+
+// Image is an upload ready to be stored.
+type Image struct {
+	Data      []byte
+	MimeType  string
+	Extension string
+	Width     int
+	Height    int
+}
+
+// Process sniffs the type of data, strips its metadata and reads its
+// dimensions.
+func Process(data []byte) (*Image, error) {
+	detected := mimetype.Detect(data)
+	strip, ok := strippers[detected.String()]
+	if !ok {
+		return nil, ErrUnsupportedType
+	}
+
+	stripped, err := strip(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(stripped))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return nil, ErrMalformed
+	}
+
+	return &Image{
+		Data:      stripped,
+		MimeType:  detected.String(),
+		Extension: detected.Extension(),
+		Width:     cfg.Width,
+		Height:    cfg.Height,
+	}, nil
+}
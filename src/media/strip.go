@@ -0,0 +1,148 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// stripJPEG drops the APP1 (EXIF, XMP), APP13 (IPTC) and comment segments.
+// The colour profile in APP2 is kept. Image data is copied untouched, so
+// orientation stored in EXIF is lost but quality is not.
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, ErrMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+
+	pos := 2
+	for {
+		if pos >= len(data) || data[pos] != 0xFF {
+			return nil, ErrMalformed
+		}
+		// Markers may be preceded by any number of fill bytes.
+		for pos < len(data) && data[pos] == 0xFF {
+			pos++
+		}
+		if pos >= len(data) {
+			return nil, ErrMalformed
+		}
+		marker := data[pos]
+		start := pos - 1
+		pos++
+
+		switch {
+		case marker == 0xD9:
+			out.Write(data[start:pos])
+			return out.Bytes(), nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			out.Write(data[start:pos])
+			continue
+		}
+
+		if pos+2 > len(data) {
+			return nil, ErrMalformed
+		}
+		end := pos + int(binary.BigEndian.Uint16(data[pos:]))
+		if end > len(data) || end < pos+2 {
+			return nil, ErrMalformed
+		}
+
+		// The scan is followed by entropy-coded data up to the end of
+		// the image, which is copied as is.
+		if marker == 0xDA {
+			out.Write(data[start:])
+			return out.Bytes(), nil
+		}
+
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out.Write(data[start:end])
+		}
+		pos = end
+	}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// pngMetadata lists the chunks dropped from PNGs.
+var pngMetadata = map[string]bool{"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true}
+
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrMalformed
+	}
+
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(pngSignature)
+
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		end := pos + 12 + length
+		if length < 0 || end > len(data) || end < pos {
+			return nil, ErrMalformed
+		}
+
+		if !pngMetadata[chunkType] {
+			out.Write(data[pos:end])
+		}
+		pos = end
+
+		if chunkType == "IEND" {
+			return out.Bytes(), nil
+		}
+	}
+
+	return nil, ErrMalformed
+}
+
+// VP8X flags announcing EXIF and XMP chunks.
+const (
+	webpFlagEXIF = 0x08
+	webpFlagXMP  = 0x04
+)
+
+// stripWebP drops the EXIF and XMP chunks and clears the flags announcing
+// them.
+func stripWebP(data []byte) ([]byte, error) {
+	if len(data) < 12 || string(data[:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, ErrMalformed
+	}
+
+	out := make([]byte, 12, len(data))
+	copy(out, data[:12])
+
+	pos := 12
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, ErrMalformed
+		}
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		end := pos + 8 + size + size%2
+		if size < 0 || end > len(data) || end < pos {
+			return nil, ErrMalformed
+		}
+
+		switch fourCC {
+		case "EXIF", "XMP ":
+		case "VP8X":
+			chunk := append([]byte(nil), data[pos:end]...)
+			if size > 0 {
+				chunk[8] &^= webpFlagEXIF | webpFlagXMP
+			}
+			out = append(out, chunk...)
+		default:
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(out)-8))
+	return out, nil
+}
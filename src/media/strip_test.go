@@ -0,0 +1,190 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// jpegSegment builds a marker segment with its length field.
+func jpegSegment(marker byte, payload string) []byte {
+	return concat([]byte{0xFF, marker}, binary.BigEndian.AppendUint16(nil, uint16(len(payload)+2)), []byte(payload))
+}
+
+// pngChunk builds a chunk with a zero CRC, which stripPNG does not check.
+func pngChunk(chunkType string, payload string) []byte {
+	return concat(binary.BigEndian.AppendUint32(nil, uint32(len(payload))), []byte(chunkType), []byte(payload), make([]byte, 4))
+}
+
+func webpChunk(fourCC string, payload []byte) []byte {
+	chunk := concat([]byte(fourCC), binary.LittleEndian.AppendUint32(nil, uint32(len(payload))), payload)
+	if len(payload)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+	return chunk
+}
+
+func webpFile(chunks ...[]byte) []byte {
+	body := concat(append([][]byte{[]byte("WEBP")}, chunks...)...)
+	return concat([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body))), body)
+}
+
+var (
+	jpegSOI   = []byte{0xFF, 0xD8}
+	jpegJFIF  = jpegSegment(0xE0, "JFIF\x00\x01\x01")
+	jpegEXIF  = jpegSegment(0xE1, "Exif\x00\x00secret")
+	jpegIPTC  = jpegSegment(0xED, "Photoshop 3.0\x00secret")
+	jpegCOM   = jpegSegment(0xFE, "secret")
+	jpegICC   = jpegSegment(0xE2, "ICC_PROFILE\x00")
+	jpegScan  = concat(jpegSegment(0xDA, "\x01\x01\x00\x00\x3F\x00"), []byte{0x12, 0xFF, 0x00, 0x34, 0xFF, 0xD0, 0x56, 0xFF, 0xD9})
+	pngIHDR   = pngChunk("IHDR", "\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+	pngIDAT   = pngChunk("IDAT", "pixels")
+	pngIEND   = pngChunk("IEND", "")
+	webpImage = webpChunk("VP8 ", []byte("odd"))
+)
+
+func TestStripJPEG(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"no metadata", concat(jpegSOI, jpegJFIF, jpegScan), concat(jpegSOI, jpegJFIF, jpegScan)},
+		{"metadata removed, profile kept", concat(jpegSOI, jpegJFIF, jpegEXIF, jpegICC, jpegIPTC, jpegCOM, jpegScan), concat(jpegSOI, jpegJFIF, jpegICC, jpegScan)},
+		{"fill bytes", concat(jpegSOI, []byte{0xFF}, jpegEXIF, jpegScan), concat(jpegSOI, jpegScan)},
+		{"end without scan", concat(jpegSOI, jpegCOM, []byte{0xFF, 0xD9}), concat(jpegSOI, []byte{0xFF, 0xD9})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripJPEG(tt.in)
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("stripJPEG() = %x, %v, want %x", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	signature := []byte(pngSignature)
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"no metadata", concat(signature, pngIHDR, pngIDAT, pngIEND), concat(signature, pngIHDR, pngIDAT, pngIEND)},
+		{
+			"metadata removed",
+			concat(signature, pngIHDR, pngChunk("tEXt", "Author\x00secret"), pngChunk("eXIf", "secret"), pngIDAT, pngChunk("iTXt", "secret"), pngChunk("zTXt", "secret"), pngChunk("tIME", "1234567"), pngIEND),
+			concat(signature, pngIHDR, pngIDAT, pngIEND),
+		},
+		{"data after end dropped", concat(signature, pngIHDR, pngIDAT, pngIEND, []byte("trailer")), concat(signature, pngIHDR, pngIDAT, pngIEND)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripPNG(tt.in)
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("stripPNG() = %x, %v, want %x", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripWebP(t *testing.T) {
+	vp8x := func(flags byte) []byte {
+		return webpChunk("VP8X", []byte{flags, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	}
+	exif := webpChunk("EXIF", []byte("secret"))
+	xmp := webpChunk("XMP ", []byte("<x:xmpmeta/>"))
+	const alpha = 0x10
+
+	tests := []struct {
+		name string
+		in   []byte
+		want []byte
+	}{
+		{"simple", webpFile(webpImage), webpFile(webpImage)},
+		{"metadata removed", webpFile(vp8x(alpha|webpFlagEXIF|webpFlagXMP), webpImage, exif, xmp), webpFile(vp8x(alpha), webpImage)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := stripWebP(tt.in)
+			if err != nil || !bytes.Equal(got, tt.want) {
+				t.Errorf("stripWebP() = %x, %v, want %x", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		strip func([]byte) ([]byte, error)
+		in    []byte
+	}{
+		{"jpeg empty", stripJPEG, nil},
+		{"jpeg wrong magic", stripJPEG, []byte("GIF89a")},
+		{"jpeg segment overruns", stripJPEG, concat(jpegSOI, []byte{0xFF, 0xE1, 0xFF, 0xFF, 0x00})},
+		{"jpeg length too short", stripJPEG, concat(jpegSOI, []byte{0xFF, 0xE1, 0x00, 0x01}, jpegScan)},
+		{"jpeg garbage between segments", stripJPEG, concat(jpegSOI, []byte{0x00}, jpegScan)},
+		{"jpeg only fill bytes", stripJPEG, concat(jpegSOI, []byte{0xFF, 0xFF})},
+		{"png wrong magic", stripPNG, []byte("\x89PNX\r\n\x1a\n")},
+		{"png no end", stripPNG, concat([]byte(pngSignature), pngIHDR, pngIDAT)},
+		{"png chunk overruns", stripPNG, concat([]byte(pngSignature), []byte{0xFF, 0xFF, 0xFF, 0xFF}, []byte("IDAT"))},
+		{"webp wrong magic", stripWebP, []byte("RIFF\x00\x00\x00\x00WAVE")},
+		{"webp chunk overruns", stripWebP, concat([]byte("RIFF\x00\x00\x00\x00WEBP"), []byte("VP8 \xFF\xFF\xFF\xFF"))},
+		{"webp short chunk header", stripWebP, concat([]byte("RIFF\x00\x00\x00\x00WEBP"), []byte("VP8"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.strip(tt.in); !errors.Is(err, ErrMalformed) {
+				t.Errorf("err = %v, want ErrMalformed", err)
+			}
+		})
+	}
+}
+
+// TestStripTruncated cuts well-formed files at every length. Cuts inside
+// the headers must be reported as malformed, and none may panic.
+func TestStripTruncated(t *testing.T) {
+	jpeg := concat(jpegSOI, jpegJFIF, jpegEXIF, jpegCOM, jpegScan)
+	png := concat([]byte(pngSignature), pngIHDR, pngChunk("tEXt", "secret"), pngIDAT, pngIEND)
+	exif := webpChunk("EXIF", []byte("secret"))
+	webp := webpFile(webpChunk("VP8X", make([]byte, 10)), webpImage, exif)
+
+	// Past the scan header, JPEG image data is copied without
+	// being parsed.
+	scanData := len(jpeg) - len(jpegScan) + 10
+	// WebP has no end marker, so a cut between chunks looks complete.
+	webpBoundaries := map[int]bool{12: true, 30: true, 30 + len(webpImage): true}
+
+	tests := []struct {
+		name      string
+		strip     func([]byte) ([]byte, error)
+		data      []byte
+		mustError func(n int) bool
+	}{
+		{"jpeg", stripJPEG, jpeg, func(n int) bool { return n < scanData }},
+		{"png", stripPNG, png, func(int) bool { return true }},
+		{"webp", stripWebP, webp, func(n int) bool { return !webpBoundaries[n] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for n := 0; n < len(tt.data); n++ {
+				_, err := tt.strip(tt.data[:n])
+				if tt.mustError(n) && !errors.Is(err, ErrMalformed) {
+					t.Errorf("cut at %d: err = %v, want ErrMalformed", n, err)
+				}
+			}
+		})
+	}
+}
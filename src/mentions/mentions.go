@@ -0,0 +1,76 @@
+// Package mentions finds the @handle mentions in post content.
+package mentions
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Match is a mention found in content. Start and End are offsets in
+// Unicode code points, covering the '@' and the handle.
+type Match struct {
+	Handle string
+	Start  int
+	End    int
+}
+
+// Extract returns every mention in content, in order.
+//
+// A mention is an '@' followed by handle characters: ASCII letters,
+// digits and underscores. Dots, hyphens and other punctuation end it, but
+// other letters do not, so "@josé" is not a mention of "jos". The '@'
+// must not follow a word character, so email addresses are skipped.
+func Extract(content string) []Match {
+	var matches []Match
+
+	var prev rune
+	offset := 0
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+		if r != '@' || isEmailRune(prev) {
+			prev = r
+			i += size
+			offset++
+			continue
+		}
+
+		start := i + size
+		end := start
+		for end < len(content) {
+			next, nextSize := utf8.DecodeRuneInString(content[end:])
+			if !isHandleRune(next) {
+				break
+			}
+			end += nextSize
+		}
+		handle := content[start:end]
+
+		next, _ := utf8.DecodeRuneInString(content[end:])
+		if handle != "" && (end == len(content) || !isWordRune(next)) {
+			matches = append(matches, Match{Handle: handle, Start: offset, End: offset + 1 + len(handle)})
+		}
+
+		prev = r
+		if handle != "" {
+			prev, _ = utf8.DecodeLastRuneInString(handle)
+		}
+		offset += utf8.RuneCountInString(content[i:end])
+		i = end
+	}
+
+	return matches
+}
+
+func isHandleRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsMark(r) || unicode.IsNumber(r)
+}
+
+// isEmailRune reports whether r can come right before the '@' of an
+// email address.
+func isEmailRune(r rune) bool {
+	return r == '.' || r == '-' || isWordRune(r)
+}
@@ -0,0 +1,39 @@
+package mentions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Match
+	}{
+		{"none", "no mentions here", nil},
+		{"single", "hi @bob", []Match{{"bob", 3, 7}}},
+		{"several in order", "@bob, @carol!", []Match{{"bob", 0, 4}, {"carol", 6, 12}}},
+		{"parentheses", "(@bob)", []Match{{"bob", 1, 5}}},
+		{"trailing dot and hyphen", "ask @bob. or @carol-", []Match{{"bob", 4, 8}, {"carol", 13, 19}}},
+		{"dot and hyphen end handle", "@bob.smith @mary-jane", []Match{{"bob", 0, 4}, {"mary", 11, 16}}},
+		{"underscore and digits", "@bob_2", []Match{{"bob_2", 0, 6}}},
+		{"email skipped", "mail bob@example.com", nil},
+		{"second at skipped", "@bob@carol", []Match{{"bob", 0, 4}}},
+		{"double at", "@@bob", []Match{{"bob", 1, 5}}},
+		{"bare at", "@ bob @", nil},
+		{"non-ascii letter", "@josé @日本", nil},
+		{"combining mark", "@jose\u0301 @bob", []Match{{"bob", 7, 11}}},
+		{"offsets in code points", "café @bob", []Match{{"bob", 5, 9}}},
+		{"astral rune before", "🚀 @bob", []Match{{"bob", 2, 6}}},
+		{"emoji is a boundary", "🚀@bob🚀", []Match{{"bob", 1, 5}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,8 +2,10 @@ package middlewares
 
 import (
 	"api/src/auth"
+	"api/src/requestid"
 	"api/src/responses"
 	"net/http"
+	"strings"
 )
 
 func Auth(next http.HandlerFunc) http.HandlerFunc {
@@ -17,3 +19,17 @@ func Auth(next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// RequestID tags every request with an ID, reusing a well-formed incoming
+// X-Request-ID header, and echoes it back in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if id == "" || len(id) > 64 || strings.ContainsFunc(id, func(c rune) bool { return c < '!' || c > '~' }) {
+			id = requestid.New()
+		}
+
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
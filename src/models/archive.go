@@ -0,0 +1,21 @@
+package models
+
+// Archive counts the posts of a user per year and month, newest first.
+// Months are calendar months in UTC.
+type Archive struct {
+	UserID string        `json:"user_id"`
+	Total  int           `json:"total"`
+	Years  []ArchiveYear `json:"years"`
+}
+
+type ArchiveYear struct {
+	Year   int            `json:"year"`
+	Count  int            `json:"count"`
+	Months []ArchiveMonth `json:"months"`
+}
+
+type ArchiveMonth struct {
+	Year  int `json:"-"`
+	Month int `json:"month"`
+	Count int `json:"count"`
+}
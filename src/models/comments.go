@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Comment struct {
+	ID        uuid.UUID  `json:"id"`
+	PostID    string     `json:"post_id"`
+	UserID    string     `json:"user_id"`
+	ParentID  *string    `json:"parent_id"`
+	Depth     int        `json:"depth"`
+	Content   string     `json:"content"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt *time.Time `json:"updated_at"`
+	Replies   []Comment  `json:"replies,omitempty"`
+}
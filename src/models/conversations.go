@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message policies: who may start conversations with a user.
+const (
+	MessagePolicyEveryone = "everyone"
+	MessagePolicyNobody   = "nobody"
+)
+
+// Conversation is a private thread between two or more users, as seen by
+// one of its members.
+type Conversation struct {
+	ID      uuid.UUID `json:"id"`
+	IsGroup bool      `json:"is_group"`
+	// Members are the users who have not left, including the viewer.
+	Members       []UserSummary `json:"members"`
+	Muted         bool          `json:"muted"`
+	UnreadCount   int           `json:"unread_count"`
+	LastMessage   *Message      `json:"last_message"`
+	CreatedAt     time.Time     `json:"created_at"`
+	LastMessageAt time.Time     `json:"last_message_at"`
+}
+
+type Message struct {
+	ID             uuid.UUID `json:"id"`
+	ConversationID uuid.UUID `json:"conversation_id"`
+	SenderID       string    `json:"sender_id"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Media is an uploaded image. Its content is served by GET /media/{id}.
+type Media struct {
+	ID        uuid.UUID  `json:"id"`
+	MimeType  string     `json:"mime_type"`
+	Size      int64      `json:"size"`
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+
+	StorageKey string `json:"-"`
+}
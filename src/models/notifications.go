@@ -0,0 +1,18 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification is a message for one user. Payload depends on Kind.
+type Notification struct {
+	ID        uuid.UUID       `json:"id"`
+	UserID    string          `json:"user_id"`
+	Kind      string          `json:"kind"`
+	Payload   json.RawMessage `json:"payload" swaggertype:"object"`
+	ReadAt    *time.Time      `json:"read_at"`
+	CreatedAt time.Time       `json:"created_at"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// Page is one slice of a cursor-paginated list. NextCursor is empty on the
+// last page.
+type Page[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -13,4 +13,51 @@ type Posts struct {
 	UserID    string     `json:"user_id" validate:"required"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt *time.Time `json:"updated_at"`
+
+	Tags          []string       `json:"tags"`
+	Mentions      []Mention      `json:"mentions"`
+	Media         []Media        `json:"media"`
+	CommentsCount int            `json:"comments_count"`
+	Reactions     map[string]int `json:"reactions"`
+	MyReactions   []string       `json:"my_reactions"`
+
+	// Bookmarked is only ever true for the viewer's own bookmarks.
+	// BookmarkedAt is set when listing them.
+	Bookmarked   bool       `json:"bookmarked"`
+	BookmarkedAt *time.Time `json:"bookmarked_at,omitempty"`
+
+	// Kind is "post", or "repost" for entries sharing another post in
+	// per-user listings.
+	Kind           string        `json:"kind"`
+	QuotedPostID   *string       `json:"quoted_post_id"`
+	QuotedPost     *EmbeddedPost `json:"quoted_post,omitempty"`
+	RepostedPostID *string       `json:"reposted_post_id,omitempty"`
+	RepostedPost   *EmbeddedPost `json:"reposted_post,omitempty"`
+}
+
+// EmbeddedPost is a post shown inside another one, such as the original
+// of a quote post or repost. Deleted originals become tombstones that only
+// keep their ID.
+type EmbeddedPost struct {
+	ID        uuid.UUID  `json:"id"`
+	Title     string     `json:"title,omitempty"`
+	Content   string     `json:"content,omitempty"`
+	UserID    string     `json:"user_id,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+	Deleted   bool       `json:"deleted"`
+}
+
+// Tombstone returns the embed used for a post that no longer exists.
+func Tombstone(id string) *EmbeddedPost {
+	parsed, _ := uuid.Parse(id)
+	return &EmbeddedPost{ID: parsed, Deleted: true}
+}
+
+// Mention is a user mentioned in a post's content. Start and End are
+// offsets into the content in Unicode code points and cover the "@handle"
+// text.
+type Mention struct {
+	UserID string `json:"user_id"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
 }
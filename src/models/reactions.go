@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type Reaction struct {
+	User      UserSummary `json:"user"`
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RecoveryEmail is the verified secondary address of a user.
+type RecoveryEmail struct {
+	Email      string    `json:"email"`
+	VerifiedAt time.Time `json:"verified_at"`
+}
+
+// AccountRecovery is a request to regain access to an account through its
+// recovery email. Status is pending, completed or cancelled.
+type AccountRecovery struct {
+	ID          uuid.UUID `json:"id"`
+	UserID      string    `json:"user_id"`
+	Email       string    `json:"email"`
+	Status      string    `json:"status"`
+	AvailableAt time.Time `json:"available_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+
+	// Ready and Expired are computed by the database clock.
+	Ready   bool `json:"-"`
+	Expired bool `json:"-"`
+}
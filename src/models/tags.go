@@ -0,0 +1,7 @@
+package models
+
+// Tag is a normalized hashtag and the number of posts using it.
+type Tag struct {
+	Name       string `json:"name"`
+	PostsCount int    `json:"posts_count"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// TrendingTag is a tag ranked by the trending job. Score decays with the
+// age of the posts and grows mostly with the number of distinct authors.
+type TrendingTag struct {
+	Name         string  `json:"name"`
+	Score        float64 `json:"score"`
+	PostsCount   int     `json:"posts_count"`
+	AuthorsCount int     `json:"authors_count"`
+}
+
+// Trending is the latest ranking for one window. ComputedAt is nil while
+// nothing is trending in the window.
+type Trending struct {
+	Window     string        `json:"window"`
+	ComputedAt *time.Time    `json:"computed_at"`
+	Tags       []TrendingTag `json:"tags"`
+}
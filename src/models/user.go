@@ -6,11 +6,47 @@ import (
 	"github.com/google/uuid"
 )
 
+// User is a user account. Email is only shown to the user themselves.
 type User struct {
-	ID        uuid.UUID `json:"id" validate:"-"`
+	ID             uuid.UUID `json:"id" validate:"-"`
+	Name           string    `json:"name"`
+	Email          string    `json:"email,omitempty" validate:"required,email"`
+	Password       string    `json:"password" validate:"required,min=8"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	FollowersCount *int      `json:"followers_count,omitempty" validate:"-"`
+	FollowingCount *int      `json:"following_count,omitempty" validate:"-"`
+
+	// AvatarURL falls back to the user's generated identicon. BannerURL
+	// is null until a banner is uploaded.
+	AvatarURL string  `json:"avatar_url" validate:"-"`
+	BannerURL *string `json:"banner_url" validate:"-"`
+
+	// Handle is null until the user picks one. The other profile fields
+	// are empty when unset.
+	Handle   *string `json:"handle"`
+	Bio      string  `json:"bio" validate:"max=160"`
+	Location string  `json:"location" validate:"max=100"`
+	Website  string  `json:"website" validate:"max=255"`
+	Pronouns string  `json:"pronouns" validate:"max=40"`
+}
+
+// UserSummary is the public part of a user embedded in other resources.
+type UserSummary struct {
+	ID        uuid.UUID `json:"id"`
 	Name      string    `json:"name"`
-	Email     string    `json:"email" validate:"required,email"`
-	Password  string    `json:"password" validate:"required,min=8"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Handle    *string   `json:"handle"`
+	AvatarURL string    `json:"avatar_url"`
+}
+
+// Summary returns the public part of user embedded in other resources.
+func (user User) Summary() UserSummary {
+	return UserSummary{ID: user.ID, Name: user.Name, Handle: user.Handle, AvatarURL: user.AvatarURL}
+}
+
+// HideEmail clears the email unless viewerID is the user's own ID.
+func (user *User) HideEmail(viewerID string) {
+	if user.ID.String() != viewerID {
+		user.Email = ""
+	}
 }
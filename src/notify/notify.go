@@ -0,0 +1,67 @@
+// Package notify sends in-app notifications to users.
+package notify
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"api/src/repositories"
+	"context"
+	"encoding/json"
+)
+
+// Notification kinds. Security kinds tell users about activity on their
+// account they may not have made themselves.
+const (
+	KindLogin           = "security.login"
+	KindProfileUpdated  = "security.profile_updated"
+	KindPasswordChanged = "security.password_changed"
+	KindBroadcast       = "broadcast"
+
+	KindRecoveryEmailChanged = "security.recovery_email_changed"
+	KindRecoveryRequested    = "security.recovery_requested"
+	KindRecoveryCancelled    = "security.recovery_cancelled"
+	KindRecoveryCompleted    = "security.recovery_completed"
+)
+
+// Publish stores a notification for userID. payload is encoded as JSON;
+// nil becomes an empty object.
+//
+// The notification is stored even if ctx is canceled afterwards, so
+// callers can pass the request context and still publish after writing
+// the response.
+func Publish(ctx context.Context, userID string, kind string, payload any) (*models.Notification, error) {
+	data, err := encode(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.NewNotificationsRepository(db).Create(context.WithoutCancel(ctx), userID, kind, data)
+}
+
+// Broadcast sends the same notification to every user and returns how
+// many were sent.
+func Broadcast(ctx context.Context, kind string, payload any) (int64, error) {
+	data, err := encode(payload)
+	if err != nil {
+		return 0, err
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		return 0, err
+	}
+
+	return repositories.NewNotificationsRepository(db).CreateForAll(context.WithoutCancel(ctx), kind, data)
+}
+
+func encode(payload any) ([]byte, error) {
+	if payload == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(payload)
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type bookmarks struct {
+	db *pgxpool.Pool
+}
+
+func NewBookmarksRepository(db *pgxpool.Pool) *bookmarks {
+	return &bookmarks{db}
+}
+
+// Add saves a post for userID. Saving the same post twice is a no-op.
+func (repository bookmarks) Add(ctx context.Context, userID string, postID string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return database.Retry(ctx, func() error {
+		_, err := repository.db.Exec(ctx, "INSERT INTO bookmarks (user_id, post_id, created_at) VALUES ($1, $2, CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING", userID, postID)
+		return err
+	})
+}
+
+// Remove unsaves a post. Removing a missing bookmark is a no-op.
+func (repository bookmarks) Remove(ctx context.Context, userID string, postID string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return database.Retry(ctx, func() error {
+		_, err := repository.db.Exec(ctx, "DELETE FROM bookmarks WHERE user_id = $1 AND post_id = $2", userID, postID)
+		return err
+	})
+}
+
+// FindManyByUserId lists the posts userID saved, most recently saved
+// first. The cursor position is the time the post was saved.
+func (repository bookmarks) FindManyByUserId(ctx context.Context, userID string, after *cursor.Position, limit int) ([]models.Posts, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + postColumns + ", b.created_at FROM bookmarks b JOIN posts ON posts.id = b.post_id WHERE b.user_id = $1"
+	args := []interface{}{userID}
+	argID := 2
+
+	if after != nil {
+		query += fmt.Sprintf(" AND (b.created_at, posts.id) < ($%d, $%d)", argID, argID+1)
+		args = append(args, after.CreatedAt, after.ID)
+		argID += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY b.created_at DESC, posts.id DESC LIMIT $%d", argID)
+	args = append(args, limit)
+
+	var posts []models.Posts
+	err := database.Retry(ctx, func() error {
+		posts = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var post models.Posts
+			if err := rows.Scan(append(postFields(&post), &post.BookmarkedAt)...); err != nil {
+				return err
+			}
+			posts = append(posts, post)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if posts == nil {
+		posts = []models.Posts{}
+	}
+
+	if err := NewPostsRepository(repository.db).embedOriginals(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// FindSavedByUser reports which of the given posts userID has saved.
+func (repository bookmarks) FindSavedByUser(ctx context.Context, userID string, postIDs []string) (map[string]bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var saved map[string]bool
+	err := database.Retry(ctx, func() error {
+		saved = make(map[string]bool)
+
+		rows, err := repository.db.Query(ctx, "SELECT post_id FROM bookmarks WHERE user_id = $1 AND post_id = ANY($2)", userID, postIDs)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var postID string
+			if err := rows.Scan(&postID); err != nil {
+				return err
+			}
+			saved[postID] = true
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
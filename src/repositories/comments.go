@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const commentColumns = "id, post_id, user_id, parent_id, depth, content, created_at, updated_at"
+
+func commentFields(comment *models.Comment) []interface{} {
+	return []interface{}{&comment.ID, &comment.PostID, &comment.UserID, &comment.ParentID, &comment.Depth, &comment.Content, &comment.CreatedAt, &comment.UpdatedAt}
+}
+
+type comments struct {
+	db *pgxpool.Pool
+}
+
+func NewCommentsRepository(db *pgxpool.Pool) *comments {
+	return &comments{db}
+}
+
+func (repository comments) Create(ctx context.Context, comment models.Comment) (*models.Comment, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var created models.Comment
+	err := repository.db.QueryRow(ctx, "INSERT INTO comments (id, post_id, user_id, parent_id, depth, content, created_at) VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, CURRENT_TIMESTAMP) RETURNING "+commentColumns,
+		comment.PostID, comment.UserID, comment.ParentID, comment.Depth, comment.Content).Scan(commentFields(&created)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (repository comments) FindById(ctx context.Context, id string) (*models.Comment, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var comment models.Comment
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT "+commentColumns+" FROM comments WHERE id = $1", id).Scan(commentFields(&comment)...)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (repository comments) Update(ctx context.Context, id string, content string) (*models.Comment, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var updated models.Comment
+	err := repository.db.QueryRow(ctx, "UPDATE comments SET content = $1, updated_at = NOW() WHERE id = $2 RETURNING "+commentColumns, content, id).Scan(commentFields(&updated)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+// Delete removes a comment along with all of its replies.
+func (repository comments) Delete(ctx context.Context, id string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return database.Retry(ctx, func() error {
+		_, err := repository.db.Exec(ctx, "DELETE FROM comments WHERE id = $1", id)
+		return err
+	})
+}
+
+// FindManyByPostId lists every comment of a post regardless of nesting,
+// oldest first.
+func (repository comments) FindManyByPostId(ctx context.Context, postID string, after *cursor.Position, limit int) ([]models.Comment, error) {
+	query := "SELECT " + commentColumns + " FROM comments WHERE post_id = $1"
+	args := []interface{}{postID}
+	argID := 2
+
+	if after != nil {
+		query += fmt.Sprintf(" AND (created_at, id) > ($%d, $%d)", argID, argID+1)
+		args = append(args, after.CreatedAt, after.ID)
+		argID += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", argID)
+	args = append(args, limit)
+
+	return repository.findMany(ctx, query, args...)
+}
+
+// FindRootsByPostId lists the top-level comments of a post, oldest first.
+func (repository comments) FindRootsByPostId(ctx context.Context, postID string, after *cursor.Position, limit int) ([]models.Comment, error) {
+	query := "SELECT " + commentColumns + " FROM comments WHERE post_id = $1 AND parent_id IS NULL"
+	args := []interface{}{postID}
+	argID := 2
+
+	if after != nil {
+		query += fmt.Sprintf(" AND (created_at, id) > ($%d, $%d)", argID, argID+1)
+		args = append(args, after.CreatedAt, after.ID)
+		argID += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at, id LIMIT $%d", argID)
+	args = append(args, limit)
+
+	return repository.findMany(ctx, query, args...)
+}
+
+// FindDescendants lists every reply below the given comments, oldest
+// first.
+func (repository comments) FindDescendants(ctx context.Context, rootIDs []string) ([]models.Comment, error) {
+	if len(rootIDs) == 0 {
+		return []models.Comment{}, nil
+	}
+
+	query := `WITH RECURSIVE thread AS (
+			SELECT ` + commentColumns + ` FROM comments WHERE parent_id = ANY($1)
+			UNION ALL
+			SELECT c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at, c.updated_at
+			FROM comments c JOIN thread t ON c.parent_id = t.id
+		)
+		SELECT ` + commentColumns + ` FROM thread ORDER BY created_at, id`
+
+	return repository.findMany(ctx, query, rootIDs)
+}
+
+func (repository comments) findMany(ctx context.Context, query string, args ...interface{}) ([]models.Comment, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var comments []models.Comment
+	err := database.Retry(ctx, func() error {
+		comments = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var comment models.Comment
+			if err := rows.Scan(commentFields(&comment)...); err != nil {
+				return err
+			}
+			comments = append(comments, comment)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if comments == nil {
+		comments = []models.Comment{}
+	}
+
+	return comments, nil
+}
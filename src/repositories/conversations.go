@@ -0,0 +1,388 @@
+package repositories
+
+import (
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// conversationColumns read a conversation as seen by the member m. Unread
+// messages are those from others since the member last read or joined.
+var conversationColumns = `c.id, c.is_group, m.muted,
+	(SELECT COUNT(*) FROM messages WHERE messages.conversation_id = c.id AND messages.sender_id <> m.user_id
+		AND messages.created_at > COALESCE(m.last_read_at, m.joined_at)),
+	(SELECT COALESCE(jsonb_agg(jsonb_build_object('id', u.id, 'name', u.name, 'handle', u.handle, 'avatar_url', ` + avatarURL("u") + `) ORDER BY u.name), '[]') FROM conversation_members members
+		JOIN users u ON u.id = members.user_id WHERE members.conversation_id = c.id AND members.left_at IS NULL),
+	last.id, last.sender_id, last.content, last.created_at, c.created_at, c.last_message_at`
+
+const conversationFrom = ` FROM conversation_members m
+	JOIN conversations c ON c.id = m.conversation_id
+	LEFT JOIN LATERAL (SELECT id, sender_id, content, created_at FROM messages WHERE messages.conversation_id = c.id
+		ORDER BY created_at DESC, id DESC LIMIT 1) last ON TRUE`
+
+const messageColumns = "id, conversation_id, sender_id, content, created_at"
+
+func scanConversation(row pgx.Row) (models.Conversation, error) {
+	var conversation models.Conversation
+	var lastID *uuid.UUID
+	var lastSenderID, lastContent *string
+	var lastCreatedAt *time.Time
+
+	err := row.Scan(&conversation.ID, &conversation.IsGroup, &conversation.Muted, &conversation.UnreadCount, &conversation.Members,
+		&lastID, &lastSenderID, &lastContent, &lastCreatedAt, &conversation.CreatedAt, &conversation.LastMessageAt)
+	if err != nil {
+		return conversation, err
+	}
+
+	if lastID != nil {
+		conversation.LastMessage = &models.Message{
+			ID:             *lastID,
+			ConversationID: conversation.ID,
+			SenderID:       *lastSenderID,
+			Content:        *lastContent,
+			CreatedAt:      *lastCreatedAt,
+		}
+	}
+	return conversation, nil
+}
+
+func messageFields(message *models.Message) []interface{} {
+	return []interface{}{&message.ID, &message.ConversationID, &message.SenderID, &message.Content, &message.CreatedAt}
+}
+
+type conversations struct {
+	db *pgxpool.Pool
+}
+
+func NewConversationsRepository(db *pgxpool.Pool) *conversations {
+	return &conversations{db}
+}
+
+// Start opens a conversation between creatorID and memberIDs, which must
+// not include the creator. A one-to-one conversation between the same two
+// users is reused, bringing back whoever left it. It reports whether a
+// new conversation was created.
+func (repository conversations) Start(ctx context.Context, creatorID string, memberIDs []string) (string, bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	defer tx.Rollback(ctx)
+
+	if len(memberIDs) == 1 {
+		pair := []string{creatorID, memberIDs[0]}
+		if pair[1] < pair[0] {
+			pair[0], pair[1] = pair[1], pair[0]
+		}
+
+		// Two users starting a conversation with each other at the same
+		// time must end up in the same one.
+		_, err = tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('conversation:' || $1 || ':' || $2))", pair[0], pair[1])
+		if err != nil {
+			return "", false, err
+		}
+
+		var existingID string
+		err = tx.QueryRow(ctx, `SELECT c.id FROM conversations c
+			JOIN conversation_members a ON a.conversation_id = c.id AND a.user_id = $1
+			JOIN conversation_members b ON b.conversation_id = c.id AND b.user_id = $2
+			WHERE NOT c.is_group LIMIT 1`, creatorID, memberIDs[0]).Scan(&existingID)
+		if err == nil {
+			_, err = tx.Exec(ctx, "UPDATE conversation_members SET left_at = NULL, joined_at = CURRENT_TIMESTAMP WHERE conversation_id = $1 AND left_at IS NOT NULL", existingID)
+			if err != nil {
+				return "", false, err
+			}
+
+			if err := tx.Commit(ctx); err != nil {
+				return "", false, err
+			}
+			return existingID, false, nil
+		}
+		if err != pgx.ErrNoRows {
+			return "", false, err
+		}
+	}
+
+	var conversationID string
+	err = tx.QueryRow(ctx, "INSERT INTO conversations (id, is_group, created_at, last_message_at) VALUES (uuid_generate_v4(), $1, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) RETURNING id",
+		len(memberIDs) > 1).Scan(&conversationID)
+	if err != nil {
+		return "", false, err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO conversation_members (conversation_id, user_id, joined_at) SELECT $1, user_id, CURRENT_TIMESTAMP FROM unnest($2::uuid[]) AS user_id",
+		conversationID, append([]string{creatorID}, memberIDs...))
+	if err != nil {
+		return "", false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", false, err
+	}
+
+	return conversationID, true, nil
+}
+
+// FindById returns a conversation userID is a member of, or nil if they
+// are not or have left it.
+func (repository conversations) FindById(ctx context.Context, id string, userID string) (*models.Conversation, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var conversation models.Conversation
+	err := database.Retry(ctx, func() error {
+		var err error
+		conversation, err = scanConversation(repository.db.QueryRow(ctx, "SELECT "+conversationColumns+conversationFrom+
+			" WHERE m.user_id = $1 AND m.left_at IS NULL AND c.id = $2", userID, id))
+		return err
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &conversation, nil
+}
+
+// FindManyByUserId lists the conversations a user has not left, most
+// recently active first.
+func (repository conversations) FindManyByUserId(ctx context.Context, userID string, after *cursor.Position, limit int) ([]models.Conversation, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + conversationColumns + conversationFrom + " WHERE m.user_id = $1 AND m.left_at IS NULL"
+	args := []interface{}{userID}
+	argID := 2
+
+	if after != nil {
+		query += fmt.Sprintf(" AND (c.last_message_at, c.id) < ($%d, $%d)", argID, argID+1)
+		args = append(args, after.CreatedAt, after.ID)
+		argID += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY c.last_message_at DESC, c.id DESC LIMIT $%d", argID)
+	args = append(args, limit)
+
+	var conversations []models.Conversation
+	err := database.Retry(ctx, func() error {
+		conversations = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			conversation, err := scanConversation(rows)
+			if err != nil {
+				return err
+			}
+			conversations = append(conversations, conversation)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if conversations == nil {
+		conversations = []models.Conversation{}
+	}
+
+	return conversations, nil
+}
+
+// Send adds a message to a conversation. Sending also marks the
+// conversation as read for the sender.
+func (repository conversations) Send(ctx context.Context, conversationID string, senderID string, content string) (*models.Message, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var message models.Message
+	err = tx.QueryRow(ctx, "INSERT INTO messages (id, conversation_id, sender_id, content, created_at) VALUES (uuid_generate_v4(), $1, $2, $3, CURRENT_TIMESTAMP) RETURNING "+messageColumns,
+		conversationID, senderID, content).Scan(messageFields(&message)...)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE conversations SET last_message_at = $2 WHERE id = $1", conversationID, message.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE conversation_members SET last_read_at = $3 WHERE conversation_id = $1 AND user_id = $2", conversationID, senderID, message.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
+
+// FindMessages lists the messages of a conversation, newest first.
+func (repository conversations) FindMessages(ctx context.Context, conversationID string, after *cursor.Position, limit int) ([]models.Message, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + messageColumns + " FROM messages WHERE conversation_id = $1"
+	args := []interface{}{conversationID}
+	argID := 2
+
+	if after != nil {
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argID, argID+1)
+		args = append(args, after.CreatedAt, after.ID)
+		argID += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", argID)
+	args = append(args, limit)
+
+	var messages []models.Message
+	err := database.Retry(ctx, func() error {
+		messages = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var message models.Message
+			if err := rows.Scan(messageFields(&message)...); err != nil {
+				return err
+			}
+			messages = append(messages, message)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if messages == nil {
+		messages = []models.Message{}
+	}
+
+	return messages, nil
+}
+
+// MarkRead marks every message of a conversation so far as read by
+// userID. It reports false when they are not a member.
+func (repository conversations) MarkRead(ctx context.Context, conversationID string, userID string) (bool, error) {
+	return repository.updateMember(ctx, conversationID, userID,
+		"last_read_at = GREATEST(last_read_at, (SELECT MAX(created_at) FROM messages WHERE messages.conversation_id = conversation_members.conversation_id))")
+}
+
+// SetMuted mutes or unmutes a conversation for userID. Muted conversations
+// do not count towards CountUnread. It reports false when they are not a
+// member.
+func (repository conversations) SetMuted(ctx context.Context, conversationID string, userID string, muted bool) (bool, error) {
+	if muted {
+		return repository.updateMember(ctx, conversationID, userID, "muted = TRUE")
+	}
+	return repository.updateMember(ctx, conversationID, userID, "muted = FALSE")
+}
+
+// Leave removes userID from a conversation. It reports false when they
+// are not a member.
+func (repository conversations) Leave(ctx context.Context, conversationID string, userID string) (bool, error) {
+	return repository.updateMember(ctx, conversationID, userID, "left_at = CURRENT_TIMESTAMP")
+}
+
+func (repository conversations) updateMember(ctx context.Context, conversationID string, userID string, set string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := repository.db.Exec(ctx, "UPDATE conversation_members SET "+set+" WHERE conversation_id = $1 AND user_id = $2 AND left_at IS NULL", conversationID, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// CountUnread counts the unread messages of a user across the
+// conversations they have not muted or left.
+func (repository conversations) CountUnread(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, `SELECT COUNT(*) FROM conversation_members m
+			JOIN messages ON messages.conversation_id = m.conversation_id
+			WHERE m.user_id = $1 AND m.left_at IS NULL AND NOT m.muted AND messages.sender_id <> m.user_id
+			AND messages.created_at > COALESCE(m.last_read_at, m.joined_at)`, userID).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindMessagePolicies returns who may message each of userIDs. Unknown
+// users are missing from the result.
+func (repository conversations) FindMessagePolicies(ctx context.Context, userIDs []string) (map[string]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var policies map[string]string
+	err := database.Retry(ctx, func() error {
+		policies = make(map[string]string)
+
+		rows, err := repository.db.Query(ctx, "SELECT id, message_policy FROM users WHERE id = ANY($1::uuid[])", userIDs)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var userID, policy string
+			if err := rows.Scan(&userID, &policy); err != nil {
+				return err
+			}
+			policies[userID] = policy
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+func (repository conversations) SetMessagePolicy(ctx context.Context, userID string, policy string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := repository.db.Exec(ctx, "UPDATE users SET message_policy = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", userID, policy)
+	return err
+}
@@ -0,0 +1,11 @@
+package repositories
+
+// ImageURL and IdenticonURL build in Go the URLs that avatarURL and
+// bannerURL build in SQL.
+func ImageURL(key string) string {
+	return imageURLPrefix + key
+}
+
+func IdenticonURL(userID string) string {
+	return identiconURLPrefix + userID + identiconURLSuffix
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type followers struct {
+	db *pgxpool.Pool
+}
+
+func NewFollowersRepository(db *pgxpool.Pool) *followers {
+	return &followers{db}
+}
+
+// Follow makes followerID follow followedID. It reports false when the
+// relationship already existed.
+func (repository followers) Follow(ctx context.Context, followerID string, followedID string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := repository.db.Exec(ctx, "INSERT INTO followers (follower_id, followed_id, created_at) VALUES ($1, $2, CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING", followerID, followedID)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// Unfollow removes the relationship. It reports false when followerID was
+// not following followedID.
+func (repository followers) Unfollow(ctx context.Context, followerID string, followedID string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var deleted bool
+	err := database.Retry(ctx, func() error {
+		tag, err := repository.db.Exec(ctx, "DELETE FROM followers WHERE follower_id = $1 AND followed_id = $2", followerID, followedID)
+		if err != nil {
+			return err
+		}
+		deleted = deleted || tag.RowsAffected() == 1
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
+
+// FindFollowers lists the users following userID, most recent first.
+func (repository followers) FindFollowers(ctx context.Context, userID string, limit int, offset int) ([]models.User, error) {
+	return repository.findMany(ctx, "SELECT "+userColumns("u")+" FROM followers f JOIN users u ON u.id = f.follower_id WHERE f.followed_id = $1 ORDER BY f.created_at DESC, u.id LIMIT $2 OFFSET $3", userID, limit, offset)
+}
+
+// FindFollowing lists the users userID follows, most recent first.
+func (repository followers) FindFollowing(ctx context.Context, userID string, limit int, offset int) ([]models.User, error) {
+	return repository.findMany(ctx, "SELECT "+userColumns("u")+" FROM followers f JOIN users u ON u.id = f.followed_id WHERE f.follower_id = $1 ORDER BY f.created_at DESC, u.id LIMIT $2 OFFSET $3", userID, limit, offset)
+}
+
+func (repository followers) findMany(ctx context.Context, query string, args ...interface{}) ([]models.User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var users []models.User
+	err := database.Retry(ctx, func() error {
+		users = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var user models.User
+			if err := rows.Scan(userFields(&user)...); err != nil {
+				return err
+			}
+			users = append(users, user)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if users == nil {
+		users = []models.User{}
+	}
+
+	return users, nil
+}
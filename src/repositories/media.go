@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ErrMediaUnavailable is returned when attaching uploads that do not
+// exist, belong to someone else or are already attached.
+var ErrMediaUnavailable = errors.New("media not found or already attached")
+
+const mediaColumns = "id, mime_type, size, width, height, created_at, storage_key"
+
+func mediaFields(media *models.Media) []interface{} {
+	return []interface{}{&media.ID, &media.MimeType, &media.Size, &media.Width, &media.Height, &media.CreatedAt, &media.StorageKey}
+}
+
+type media struct {
+	db *pgxpool.Pool
+}
+
+func NewMediaRepository(db *pgxpool.Pool) *media {
+	return &media{db}
+}
+
+// Create records an upload stored under media.StorageKey.
+func (repository media) Create(ctx context.Context, userID string, upload models.Media) (*models.Media, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var created models.Media
+	err := repository.db.QueryRow(ctx, "INSERT INTO media (id, user_id, storage_key, mime_type, size, width, height, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP) RETURNING "+mediaColumns,
+		upload.ID, userID, upload.StorageKey, upload.MimeType, upload.Size, upload.Width, upload.Height).Scan(mediaFields(&created)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+func (repository media) FindById(ctx context.Context, id string) (*models.Media, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var found models.Media
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT "+mediaColumns+" FROM media WHERE id = $1", id).Scan(mediaFields(&found)...)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &found, nil
+}
+
+// DeleteUnattached deletes up to limit uploads that have not been attached
+// to a post for longer than ttl, oldest first, and returns their storage
+// keys.
+func (repository media) DeleteUnattached(ctx context.Context, ttl time.Duration, limit int) ([]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// post_id is checked again outside the subquery so uploads attached
+	// meanwhile are kept.
+	rows, err := repository.db.Query(ctx, `DELETE FROM media WHERE post_id IS NULL AND id IN (
+			SELECT id FROM media WHERE post_id IS NULL AND created_at < LOCALTIMESTAMP - make_interval(secs => $1)
+			ORDER BY created_at LIMIT $2)
+		RETURNING storage_key`, ttl.Seconds(), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const notificationColumns = "id, user_id, kind, payload, read_at, created_at"
+
+func notificationFields(notification *models.Notification) []interface{} {
+	return []interface{}{&notification.ID, &notification.UserID, &notification.Kind, &notification.Payload, &notification.ReadAt, &notification.CreatedAt}
+}
+
+type notifications struct {
+	db *pgxpool.Pool
+}
+
+func NewNotificationsRepository(db *pgxpool.Pool) *notifications {
+	return &notifications{db}
+}
+
+func (repository notifications) Create(ctx context.Context, userID string, kind string, payload []byte) (*models.Notification, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var created models.Notification
+	err := repository.db.QueryRow(ctx, "INSERT INTO notifications (id, user_id, kind, payload, created_at) VALUES (uuid_generate_v4(), $1, $2, $3, CURRENT_TIMESTAMP) RETURNING "+notificationColumns,
+		userID, kind, payload).Scan(notificationFields(&created)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// CreateForAll sends the same notification to every user and returns how
+// many were created.
+func (repository notifications) CreateForAll(ctx context.Context, kind string, payload []byte) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := repository.db.Exec(ctx, "INSERT INTO notifications (id, user_id, kind, payload, created_at) SELECT uuid_generate_v4(), id, $1, $2, CURRENT_TIMESTAMP FROM users", kind, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+// FindManyByUserId lists a user's notifications, newest first, optionally
+// only those of one kind.
+func (repository notifications) FindManyByUserId(ctx context.Context, userID string, kind string, after *cursor.Position, limit int) ([]models.Notification, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = $1"
+	args := []interface{}{userID}
+	argID := 2
+
+	if kind != "" {
+		query += fmt.Sprintf(" AND kind = $%d", argID)
+		args = append(args, kind)
+		argID++
+	}
+
+	if after != nil {
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argID, argID+1)
+		args = append(args, after.CreatedAt, after.ID)
+		argID += 2
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", argID)
+	args = append(args, limit)
+
+	var notifications []models.Notification
+	err := database.Retry(ctx, func() error {
+		notifications = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var notification models.Notification
+			if err := rows.Scan(notificationFields(&notification)...); err != nil {
+				return err
+			}
+			notifications = append(notifications, notification)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if notifications == nil {
+		notifications = []models.Notification{}
+	}
+
+	return notifications, nil
+}
+
+// MarkRead marks one of the user's notifications as read. It reports
+// false when the user has no such notification.
+func (repository notifications) MarkRead(ctx context.Context, userID string, id string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var readID string
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "UPDATE notifications SET read_at = COALESCE(read_at, CURRENT_TIMESTAMP) WHERE id = $1 AND user_id = $2 RETURNING id", id, userID).Scan(&readID)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// MarkAllRead marks every unread notification of the user as read and
+// returns how many changed.
+func (repository notifications) MarkAllRead(ctx context.Context, userID string) (int64, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tag, err := repository.db.Exec(ctx, "UPDATE notifications SET read_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND read_at IS NULL", userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return tag.RowsAffected(), nil
+}
+
+func (repository notifications) CountUnread(ctx context.Context, userID string) (int, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var count int
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL", userID).Scan(&count)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
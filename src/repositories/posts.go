@@ -1,49 +1,136 @@
 package repositories
 
 import (
+	"api/src/cursor"
+	"api/src/database"
+	"api/src/events"
+	"api/src/handles"
+	"api/src/hashtags"
+	"api/src/mentions"
 	"api/src/models"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// postColumns is selected by every query returning models.Posts, in the
+// order expected by postFields.
+const postColumns = `id, title, content, user_id, created_at, updated_at,
+	(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id),
+	(SELECT COALESCE(jsonb_object_agg(type, total), '{}') FROM (SELECT type, COUNT(*) AS total FROM reactions WHERE reactions.post_id = posts.id GROUP BY type) counts),
+	quoted_post_id, 'post', NULL::uuid,
+	(SELECT COALESCE(array_agg(tags.name ORDER BY tags.name), '{}') FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE post_tags.post_id = posts.id),
+	(SELECT COALESCE(jsonb_agg(jsonb_build_object('user_id', user_id, 'start', start_offset, 'end', end_offset) ORDER BY start_offset), '[]') FROM mentions WHERE mentions.post_id = posts.id),
+	(SELECT COALESCE(jsonb_agg(jsonb_build_object('id', id, 'mime_type', mime_type, 'size', size, 'width', width, 'height', height) ORDER BY position), '[]') FROM media WHERE media.post_id = posts.id)`
+
+// repostColumns selects a repost entry from reposts r in the same shape
+// as postColumns, so reposts can be listed alongside posts.
+const repostColumns = `r.id, '', '', r.user_id, r.created_at, NULL::timestamp, 0::bigint, '{}'::jsonb,
+	NULL::uuid, 'repost', r.post_id, '{}'::text[], '[]'::jsonb, '[]'::jsonb`
+
+func postFields(post *models.Posts) []interface{} {
+	return []interface{}{&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt, &post.UpdatedAt, &post.CommentsCount, &post.Reactions,
+		&post.QuotedPostID, &post.Kind, &post.RepostedPostID, &post.Tags, &post.Mentions, &post.Media}
+}
+
 type posts struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewPostsRepository(db *pgx.Conn) *posts {
+func NewPostsRepository(db *pgxpool.Pool) *posts {
 	return &posts{db}
 }
 
-func (repository posts) Create(post models.Posts) (string, error) {
+// Create inserts a post and attaches the given uploads of its author, in
+// order. It fails with ErrMediaUnavailable if any of them is missing or
+// already attached.
+func (repository posts) Create(ctx context.Context, post models.Posts, mediaIDs []string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var postId string
 
-	tx, err := repository.db.Begin(context.Background())
+	tx, err := repository.db.Begin(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
-	err = tx.QueryRow(context.Background(), "INSERT INTO posts (id, title, content, user_id, created_at) VALUES (uuid_generate_v4(), $1, $2, $3, CURRENT_TIMESTAMP) RETURNING id", post.Title, post.Content, post.UserID).Scan(&postId)
+	err = tx.QueryRow(ctx, "INSERT INTO posts (id, title, content, user_id, quoted_post_id, created_at) VALUES (uuid_generate_v4(), $1, $2, $3, $4, CURRENT_TIMESTAMP) RETURNING id", post.Title, post.Content, post.UserID, post.QuotedPostID).Scan(&postId)
 	if err != nil {
-		tx.Rollback(context.Background())
+		tx.Rollback(ctx)
 		return "", err
 	}
 
-	err = tx.Commit(context.Background())
+	err = syncTags(ctx, tx, postId, post.Content)
 	if err != nil {
+		tx.Rollback(ctx)
 		return "", err
 	}
 
+	err = syncMentions(ctx, tx, postId, post.Content)
+	if err != nil {
+		tx.Rollback(ctx)
+		return "", err
+	}
+
+	if len(mediaIDs) > 0 {
+		tag, err := tx.Exec(ctx, "UPDATE media SET post_id = $1, position = array_position($2::uuid[], id) WHERE id = ANY($2::uuid[]) AND user_id = $3 AND post_id IS NULL",
+			postId, mediaIDs, post.UserID)
+		if err != nil {
+			tx.Rollback(ctx)
+			return "", err
+		}
+		if tag.RowsAffected() != int64(len(mediaIDs)) {
+			tx.Rollback(ctx)
+			return "", ErrMediaUnavailable
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	// Subscribers get the post as reads return it. If it cannot be loaded
+	// they fetch it themselves.
+	created, err := repository.FindById(ctx, postId)
+	if err != nil || created == nil {
+		events.Publish(ctx, events.New(events.PostCreated, postId, post.UserID, nil))
+	} else {
+		events.Publish(ctx, events.New(events.PostCreated, postId, post.UserID, created))
+	}
+
 	return postId, nil
 }
 
-func (repository posts) Update(id string, fields map[string]interface{}) (*models.Posts, error) {
+// ErrFieldNotAllowed is returned by the Update methods for fields that
+// callers may not change.
+var ErrFieldNotAllowed = errors.New("field cannot be updated")
+
+// updatablePostColumns are the columns Update may set. The rest are owned
+// by the server or by other endpoints.
+var updatablePostColumns = map[string]bool{"title": true, "content": true}
+
+func (repository posts) Update(ctx context.Context, id string, fields map[string]interface{}) (*models.Posts, error) {
 	if len(fields) == 0 {
 		return nil, nil
 	}
 
+	for key := range fields {
+		if !updatablePostColumns[key] {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotAllowed, key)
+		}
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	query := "UPDATE posts SET"
 	args := []interface{}{}
 	argID := 1
@@ -59,61 +146,386 @@ func (repository posts) Update(id string, fields map[string]interface{}) (*model
 
 	query += fmt.Sprintf(", updated_at = NOW()")
 
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, title, content, user_id, created_at, updated_at", argID)
+	query += fmt.Sprintf(" WHERE id = $%d", argID)
 	args = append(args, id)
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if tag.RowsAffected() == 0 {
+		return nil, nil
+	}
+
+	if content, ok := fields["content"].(string); ok {
+		if err := syncTags(ctx, tx, id, content); err != nil {
+			return nil, err
+		}
+		if err := syncMentions(ctx, tx, id, content); err != nil {
+			return nil, err
+		}
+	}
+
+	// Read the post back after the tags and mentions are synced so the
+	// response includes them.
 	var updatedPost models.Posts
-	err := repository.db.QueryRow(context.Background(), query, args...).Scan(&updatedPost.ID, &updatedPost.Title, &updatedPost.Content, &updatedPost.UserID, &updatedPost.CreatedAt, &updatedPost.UpdatedAt)
+	err = tx.QueryRow(ctx, "SELECT "+postColumns+" FROM posts WHERE id = $1", id).Scan(postFields(&updatedPost)...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &updatedPost, nil
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	posts := []models.Posts{updatedPost}
+	if err := repository.embedOriginals(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	events.Publish(ctx, events.New(events.PostUpdated, id, posts[0].UserID, posts[0]))
+	return &posts[0], nil
 }
 
-func (repository posts) FindById(id string) (*models.Posts, error) {
+func (repository posts) FindById(ctx context.Context, id string) (*models.Posts, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var post models.Posts
-	err := repository.db.QueryRow(context.Background(), "SELECT id, title, content, user_id, created_at, updated_at FROM posts WHERE id = $1", id).Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt, &post.UpdatedAt)
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT "+postColumns+" FROM posts WHERE id = $1", id).Scan(postFields(&post)...)
+	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &post, nil
+
+	posts := []models.Posts{post}
+	if err := repository.embedOriginals(ctx, posts); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
 }
 
-func (repository posts) FindManyByUserId(user_id string, limit int, offset int, filters map[string]interface{}) ([]models.Posts, error) {
-	query := "SELECT id, title, content, user_id, created_at, updated_at FROM posts WHERE user_id = $1"
+func (repository posts) FindManyByUserId(ctx context.Context, user_id string, limit int, offset int, filters map[string]interface{}) ([]models.Posts, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	postsQuery := "SELECT " + postColumns + " FROM posts WHERE user_id = $1"
+	repostsQuery := "SELECT " + repostColumns + " FROM reposts r LEFT JOIN posts original ON original.id = r.post_id WHERE r.user_id = $1"
 	args := []interface{}{user_id}
 	argID := 2
 
+	// Reposts are matched against the post they share.
 	for key, value := range filters {
-		query += fmt.Sprintf(" AND %s ILIKE $%d", key, argID)
+		postsQuery += fmt.Sprintf(" AND %s ILIKE $%d", key, argID)
+		repostsQuery += fmt.Sprintf(" AND original.%s ILIKE $%d", key, argID)
 		args = append(args, fmt.Sprintf("%%%s%%", value))
 		argID++
 	}
 
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", argID, argID+1)
+	query := fmt.Sprintf("SELECT * FROM (%s UNION ALL %s) feed ORDER BY created_at DESC, id DESC LIMIT $%d OFFSET $%d", postsQuery, repostsQuery, argID, argID+1)
 	args = append(args, limit, offset)
 
-	rows, err := repository.db.Query(context.Background(), query, args...)
+	var posts []models.Posts
+	err := database.Retry(ctx, func() error {
+		posts = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+
+		defer rows.Close()
+
+		for rows.Next() {
+			var post models.Posts
+			if err := rows.Scan(postFields(&post)...); err != nil {
+				return err
+			}
+			posts = append(posts, post)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
+		log.Printf("Error fetching posts: %v", err)
 		return nil, err
 	}
 
-	defer rows.Close()
+	if posts == nil {
+		posts = []models.Posts{}
+	}
+
+	if err := repository.embedOriginals(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+func (repository posts) Delete(ctx context.Context, id string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var authorID string
+	err := database.Retry(ctx, func() error {
+		authorID = ""
+
+		tx, err := repository.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, "DELETE FROM comments WHERE post_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM reactions WHERE post_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM bookmarks WHERE post_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM post_tags WHERE post_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM mentions WHERE post_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		// Detached uploads are removed by the media garbage collector.
+		_, err = tx.Exec(ctx, "UPDATE media SET post_id = NULL WHERE post_id = $1", id)
+		if err != nil {
+			return err
+		}
+
+		err = tx.QueryRow(ctx, "DELETE FROM posts WHERE id = $1 RETURNING user_id", id).Scan(&authorID)
+		if err != nil && err != pgx.ErrNoRows {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+	if err != nil {
+		return err
+	}
+
+	if authorID != "" {
+		events.Publish(ctx, events.New(events.PostDeleted, id, authorID, map[string]string{"id": id}))
+	}
+	return nil
+}
+
+// TimelineFilters narrows the global timeline. Zero values are ignored.
+type TimelineFilters struct {
+	AuthorID    string
+	Tag         string
+	MentionedID string
+	From        *time.Time
+	To          *time.Time
+	Month       *time.Time // first instant of a calendar month, in UTC
+	After       *cursor.Position
+	SinceID     string
+}
+
+// FindTimeline lists posts from every user, newest first, ordered by
+// (created_at, id) so pages stay stable while new posts arrive.
+func (repository posts) FindTimeline(ctx context.Context, filters TimelineFilters, limit int) ([]models.Posts, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + postColumns + " FROM posts WHERE 1=1"
+	args := []interface{}{}
+	argID := 1
+
+	if filters.AuthorID != "" {
+		query += fmt.Sprintf(" AND user_id = $%d", argID)
+		args = append(args, filters.AuthorID)
+		argID++
+	}
+
+	if filters.Tag != "" {
+		query += fmt.Sprintf(" AND id IN (SELECT post_tags.post_id FROM post_tags JOIN tags ON tags.id = post_tags.tag_id WHERE tags.name = $%d)", argID)
+		args = append(args, filters.Tag)
+		argID++
+	}
+
+	if filters.MentionedID != "" {
+		query += fmt.Sprintf(" AND id IN (SELECT post_id FROM mentions WHERE user_id = $%d)", argID)
+		args = append(args, filters.MentionedID)
+		argID++
+	}
+
+	if filters.From != nil {
+		query += fmt.Sprintf(" AND created_at >= $%d", argID)
+		args = append(args, *filters.From)
+		argID++
+	}
+
+	if filters.To != nil {
+		query += fmt.Sprintf(" AND created_at < $%d", argID)
+		args = append(args, *filters.To)
+		argID++
+	}
+
+	if filters.Month != nil {
+		query += fmt.Sprintf(" AND date_trunc('month', created_at) = $%d", argID)
+		args = append(args, *filters.Month)
+		argID++
+	}
+
+	if filters.After != nil {
+		query += fmt.Sprintf(" AND (created_at, id) < ($%d, $%d)", argID, argID+1)
+		args = append(args, filters.After.CreatedAt, filters.After.ID)
+		argID += 2
+	}
+
+	if filters.SinceID != "" {
+		query += fmt.Sprintf(" AND (created_at, id) > (SELECT created_at, id FROM posts WHERE id = $%d)", argID)
+		args = append(args, filters.SinceID)
+		argID++
+	}
+
+	query += fmt.Sprintf(" ORDER BY created_at DESC, id DESC LIMIT $%d", argID)
+	args = append(args, limit)
 
 	var posts []models.Posts
-	for rows.Next() {
-		var post models.Posts
-		if err := rows.Scan(&post.ID, &post.Title, &post.Content, &post.UserID, &post.CreatedAt, &post.UpdatedAt); err != nil {
-			return nil, err
+	err := database.Retry(ctx, func() error {
+		posts = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var post models.Posts
+			if err := rows.Scan(postFields(&post)...); err != nil {
+				return err
+			}
+			posts = append(posts, post)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if posts == nil {
+		posts = []models.Posts{}
+	}
+
+	if err := repository.embedOriginals(ctx, posts); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
+
+// CountByMonth counts the posts of a user per calendar month in UTC,
+// newest month first. Months without posts are left out.
+func (repository posts) CountByMonth(ctx context.Context, userID string) ([]models.ArchiveMonth, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var months []models.ArchiveMonth
+	err := database.Retry(ctx, func() error {
+		months = nil
+
+		rows, err := repository.db.Query(ctx, `SELECT EXTRACT(YEAR FROM month)::int, EXTRACT(MONTH FROM month)::int, total
+			FROM (SELECT date_trunc('month', created_at) AS month, COUNT(*) AS total FROM posts WHERE user_id = $1 GROUP BY 1) counts
+			ORDER BY month DESC`, userID)
+		if err != nil {
+			return err
 		}
-		posts = append(posts, post)
+		defer rows.Close()
+
+		for rows.Next() {
+			var month models.ArchiveMonth
+			if err := rows.Scan(&month.Year, &month.Month, &month.Count); err != nil {
+				return err
+			}
+			months = append(months, month)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err = rows.Err(); err != nil {
-		log.Printf("Error iterating rows: %v", err)
+	if months == nil {
+		months = []models.ArchiveMonth{}
+	}
+
+	return months, nil
+}
+
+// ErrUnknownTimeZone is returned by FindOnThisDay when Postgres does not
+// know the time zone. Its zone database can lag behind Go's.
+var ErrUnknownTimeZone = errors.New("unknown time zone")
+
+// FindOnThisDay lists the posts of a user from today's date in previous
+// years, newest first. Dates are taken in timeZone, an IANA name.
+func (repository posts) FindOnThisDay(ctx context.Context, userID string, timeZone string, limit int) ([]models.Posts, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	// Timestamps are stored in the session time zone, so casting them
+	// to timestamptz before converting gives the user's local time.
+	query := `SELECT ` + postColumns + ` FROM posts, (SELECT (CURRENT_TIMESTAMP AT TIME ZONE $2)::date AS today) now
+		WHERE user_id = $1
+		AND EXTRACT(MONTH FROM created_at::timestamptz AT TIME ZONE $2) = EXTRACT(MONTH FROM now.today)
+		AND EXTRACT(DAY FROM created_at::timestamptz AT TIME ZONE $2) = EXTRACT(DAY FROM now.today)
+		AND EXTRACT(YEAR FROM created_at::timestamptz AT TIME ZONE $2) < EXTRACT(YEAR FROM now.today)
+		ORDER BY created_at DESC, id DESC LIMIT $3`
+
+	var posts []models.Posts
+	err := database.Retry(ctx, func() error {
+		posts = nil
+
+		rows, err := repository.db.Query(ctx, query, userID, timeZone, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var post models.Posts
+			if err := rows.Scan(postFields(&post)...); err != nil {
+				return err
+			}
+			posts = append(posts, post)
+		}
+
+		return rows.Err()
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "22023" { // invalid_parameter_value
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTimeZone, timeZone)
+	}
+	if err != nil {
 		return nil, err
 	}
 
@@ -121,13 +533,142 @@ func (repository posts) FindManyByUserId(user_id string, limit int, offset int,
 		posts = []models.Posts{}
 	}
 
+	if err := repository.embedOriginals(ctx, posts); err != nil {
+		return nil, err
+	}
+
 	return posts, nil
 }
 
-func (repository posts) Delete(id string) error {
-	_, err := repository.db.Exec(context.Background(), "DELETE FROM posts WHERE id = $1", id)
+// syncTags replaces the tags of a post with the hashtags found in its
+// content, creating tags that do not exist yet.
+func syncTags(ctx context.Context, tx pgx.Tx, postID string, content string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM post_tags WHERE post_id = $1", postID)
+	if err != nil {
+		return err
+	}
+
+	names := hashtags.Extract(content)
+	if len(names) == 0 {
+		return nil
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO tags (id, name, created_at) SELECT uuid_generate_v4(), name, CURRENT_TIMESTAMP FROM unnest($1::text[]) AS name ON CONFLICT (name) DO NOTHING", names)
 	if err != nil {
 		return err
 	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO post_tags (post_id, tag_id) SELECT $1, id FROM tags WHERE name = ANY($2)", postID, names)
+	return err
+}
+
+// syncMentions replaces the mentions of a post with the @handle mentions
+// found in its content. Handles are unique regardless of case, so each
+// matches at most one user; handles that match no user are not mentions.
+func syncMentions(ctx context.Context, tx pgx.Tx, postID string, content string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM mentions WHERE post_id = $1", postID)
+	if err != nil {
+		return err
+	}
+
+	matches := mentions.Extract(content)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	names := make([]string, len(matches))
+	for i, match := range matches {
+		names[i] = handles.Normalize(match.Handle)
+	}
+
+	rows, err := tx.Query(ctx, "SELECT LOWER(handle), id::text FROM users WHERE LOWER(handle) = ANY($1)", names)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byHandle := make(map[string]string)
+	for rows.Next() {
+		var handle, userID string
+		if err := rows.Scan(&handle, &userID); err != nil {
+			return err
+		}
+		byHandle[handle] = userID
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	// Free the connection for the inserts below.
+	rows.Close()
+
+	for i, match := range matches {
+		userID, ok := byHandle[names[i]]
+		if !ok {
+			continue
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO mentions (post_id, user_id, start_offset, end_offset) VALUES ($1, $2, $3, $4)", postID, userID, match.Start, match.End)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// embedOriginals loads the posts that quote posts and reposts point to.
+// Originals that no longer exist are embedded as tombstones.
+func (repository posts) embedOriginals(ctx context.Context, posts []models.Posts) error {
+	var ids []string
+	for _, post := range posts {
+		if post.QuotedPostID != nil {
+			ids = append(ids, *post.QuotedPostID)
+		}
+		if post.RepostedPostID != nil {
+			ids = append(ids, *post.RepostedPostID)
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	originals := make(map[string]models.EmbeddedPost, len(ids))
+	err := database.Retry(ctx, func() error {
+		rows, err := repository.db.Query(ctx, "SELECT id, title, content, user_id, created_at FROM posts WHERE id = ANY($1)", ids)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var original models.EmbeddedPost
+			if err := rows.Scan(&original.ID, &original.Title, &original.Content, &original.UserID, &original.CreatedAt); err != nil {
+				return err
+			}
+			originals[original.ID.String()] = original
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return err
+	}
+
+	embed := func(id string) *models.EmbeddedPost {
+		if original, ok := originals[id]; ok {
+			return &original
+		}
+		return models.Tombstone(id)
+	}
+
+	for i, post := range posts {
+		if post.QuotedPostID != nil {
+			posts[i].QuotedPost = embed(*post.QuotedPostID)
+		}
+		if post.RepostedPostID != nil {
+			posts[i].RepostedPost = embed(*post.RepostedPostID)
+		}
+	}
+
 	return nil
 }
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type reactions struct {
+	db *pgxpool.Pool
+}
+
+func NewReactionsRepository(db *pgxpool.Pool) *reactions {
+	return &reactions{db}
+}
+
+// Add records a reaction. Adding the same reaction twice is a no-op.
+func (repository reactions) Add(ctx context.Context, postID string, userID string, reactionType string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return database.Retry(ctx, func() error {
+		_, err := repository.db.Exec(ctx, "INSERT INTO reactions (post_id, user_id, type, created_at) VALUES ($1, $2, $3, CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING", postID, userID, reactionType)
+		return err
+	})
+}
+
+// Remove deletes a reaction. Removing a missing reaction is a no-op.
+func (repository reactions) Remove(ctx context.Context, postID string, userID string, reactionType string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return database.Retry(ctx, func() error {
+		_, err := repository.db.Exec(ctx, "DELETE FROM reactions WHERE post_id = $1 AND user_id = $2 AND type = $3", postID, userID, reactionType)
+		return err
+	})
+}
+
+// FindManyByPostId lists who reacted to a post, most recent first,
+// optionally only for one reaction type.
+func (repository reactions) FindManyByPostId(ctx context.Context, postID string, reactionType string, limit int, offset int) ([]models.Reaction, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT u.id, u.name, u.handle, " + avatarURL("u") + ", r.type, r.created_at FROM reactions r JOIN users u ON u.id = r.user_id WHERE r.post_id = $1"
+	args := []interface{}{postID}
+	argID := 2
+
+	if reactionType != "" {
+		query += fmt.Sprintf(" AND r.type = $%d", argID)
+		args = append(args, reactionType)
+		argID++
+	}
+
+	query += fmt.Sprintf(" ORDER BY r.created_at DESC, u.id LIMIT $%d OFFSET $%d", argID, argID+1)
+	args = append(args, limit, offset)
+
+	var reactions []models.Reaction
+	err := database.Retry(ctx, func() error {
+		reactions = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var reaction models.Reaction
+			if err := rows.Scan(&reaction.User.ID, &reaction.User.Name, &reaction.User.Handle, &reaction.User.AvatarURL, &reaction.Type, &reaction.CreatedAt); err != nil {
+				return err
+			}
+			reactions = append(reactions, reaction)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reactions == nil {
+		reactions = []models.Reaction{}
+	}
+
+	return reactions, nil
+}
+
+// FindTypesByUser returns, for each of the given posts, the reactions
+// userID has added to it.
+func (repository reactions) FindTypesByUser(ctx context.Context, userID string, postIDs []string) (map[string][]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var types map[string][]string
+	err := database.Retry(ctx, func() error {
+		types = make(map[string][]string)
+
+		rows, err := repository.db.Query(ctx, "SELECT post_id, type FROM reactions WHERE user_id = $1 AND post_id = ANY($2) ORDER BY type", userID, postIDs)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var postID, reactionType string
+			if err := rows.Scan(&postID, &reactionType); err != nil {
+				return err
+			}
+			types[postID] = append(types[postID], reactionType)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
@@ -0,0 +1,286 @@
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Recovery statuses.
+const (
+	RecoveryPending   = "pending"
+	RecoveryCompleted = "completed"
+	RecoveryCancelled = "cancelled"
+)
+
+const recoveryColumns = `id, user_id, email, status, available_at, expires_at, created_at,
+	available_at <= LOCALTIMESTAMP, expires_at <= LOCALTIMESTAMP`
+
+func recoveryFields(recovery *models.AccountRecovery) []interface{} {
+	return []interface{}{&recovery.ID, &recovery.UserID, &recovery.Email, &recovery.Status, &recovery.AvailableAt, &recovery.ExpiresAt, &recovery.CreatedAt,
+		&recovery.Ready, &recovery.Expired}
+}
+
+type recovery struct {
+	db *pgxpool.Pool
+}
+
+func NewRecoveryRepository(db *pgxpool.Pool) *recovery {
+	return &recovery{db}
+}
+
+// FindEmail returns the verified recovery email of a user, or nil if none
+// is set.
+func (repository recovery) FindEmail(ctx context.Context, userID string) (*models.RecoveryEmail, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var email *string
+	var verifiedAt *time.Time
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT recovery_email, recovery_email_verified_at FROM users WHERE id = $1", userID).Scan(&email, &verifiedAt)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if email == nil || verifiedAt == nil {
+		return nil, nil
+	}
+	return &models.RecoveryEmail{Email: *email, VerifiedAt: *verifiedAt}, nil
+}
+
+// SaveVerification records a pending recovery email for a user, replacing
+// any earlier one that was not verified yet.
+func (repository recovery) SaveVerification(ctx context.Context, userID string, email string, tokenHash string, ttl time.Duration) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM recovery_email_verifications WHERE user_id = $1", userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, "INSERT INTO recovery_email_verifications (token_hash, user_id, email, expires_at, created_at) VALUES ($1, $2, $3, LOCALTIMESTAMP + make_interval(secs => $4), CURRENT_TIMESTAMP)",
+		tokenHash, userID, email, ttl.Seconds())
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// VerifyEmail makes the address of a pending verification the user's
+// recovery email. It returns an empty user ID when the token is unknown
+// or expired.
+func (repository recovery) VerifyEmail(ctx context.Context, tokenHash string) (string, string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return "", "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, email string
+	err = tx.QueryRow(ctx, "DELETE FROM recovery_email_verifications WHERE token_hash = $1 AND expires_at > LOCALTIMESTAMP RETURNING user_id, email", tokenHash).Scan(&userID, &email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	_, err = tx.Exec(ctx, "UPDATE users SET recovery_email = $1, recovery_email_verified_at = CURRENT_TIMESTAMP WHERE id = $2", email, userID)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", "", err
+	}
+	return userID, email, nil
+}
+
+// RemoveEmail clears the recovery email of a user and cancels recoveries
+// still waiting on it.
+func (repository recovery) RemoveEmail(ctx context.Context, userID string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	return database.Retry(ctx, func() error {
+		tx, err := repository.db.Begin(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback(ctx)
+
+		_, err = tx.Exec(ctx, "UPDATE users SET recovery_email = NULL, recovery_email_verified_at = NULL WHERE id = $1", userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "DELETE FROM recovery_email_verifications WHERE user_id = $1", userID)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE account_recoveries SET status = $1, updated_at = NOW() WHERE user_id = $2 AND status = $3", RecoveryCancelled, userID, RecoveryPending)
+		if err != nil {
+			return err
+		}
+
+		return tx.Commit(ctx)
+	})
+}
+
+// FindUserByEmail returns the user whose verified recovery email is email,
+// or nil.
+func (repository recovery) FindUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT "+userColumns("users")+" FROM users WHERE LOWER(recovery_email) = LOWER($1) AND recovery_email_verified_at IS NOT NULL", email).
+			Scan(userFields(&user)...)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
+
+// Start opens a recovery for a user, available after delay and for ttl
+// after that. If one is already pending its tokens are replaced, so only
+// the latest emails work, but its cooling-off period is kept.
+func (repository recovery) Start(ctx context.Context, userID string, email string, confirmHash string, cancelHash string, delay time.Duration, ttl time.Duration) (*models.AccountRecovery, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "UPDATE account_recoveries SET status = $1, updated_at = NOW() WHERE user_id = $2 AND status = $3 AND expires_at <= LOCALTIMESTAMP", RecoveryCancelled, userID, RecoveryPending)
+	if err != nil {
+		return nil, err
+	}
+
+	var recovery models.AccountRecovery
+	err = tx.QueryRow(ctx, `INSERT INTO account_recoveries (id, user_id, email, confirm_token_hash, cancel_token_hash, status, available_at, expires_at, created_at)
+		VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, LOCALTIMESTAMP + make_interval(secs => $6), LOCALTIMESTAMP + make_interval(secs => $7), CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) WHERE status = 'pending'
+		DO UPDATE SET confirm_token_hash = EXCLUDED.confirm_token_hash, cancel_token_hash = EXCLUDED.cancel_token_hash, email = EXCLUDED.email, updated_at = NOW()
+		RETURNING `+recoveryColumns,
+		userID, email, confirmHash, cancelHash, RecoveryPending, delay.Seconds(), (delay + ttl).Seconds()).Scan(recoveryFields(&recovery)...)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return &recovery, nil
+}
+
+// FindByConfirmToken returns the recovery a confirmation token belongs to,
+// or nil.
+func (repository recovery) FindByConfirmToken(ctx context.Context, confirmHash string) (*models.AccountRecovery, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var recovery models.AccountRecovery
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT "+recoveryColumns+" FROM account_recoveries WHERE confirm_token_hash = $1", confirmHash).Scan(recoveryFields(&recovery)...)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &recovery, nil
+}
+
+// Complete finishes a pending, ready recovery: the recovery email becomes
+// the primary email and the password is replaced. It returns the previous
+// primary email, or "" when the recovery can no longer be completed.
+func (repository recovery) Complete(ctx context.Context, id string, passwordHash string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	var userID, email string
+	err = tx.QueryRow(ctx, `UPDATE account_recoveries SET status = $1, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND available_at <= LOCALTIMESTAMP AND expires_at > LOCALTIMESTAMP
+		RETURNING user_id, email`, RecoveryCompleted, id, RecoveryPending).Scan(&userID, &email)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	var previousEmail string
+	err = tx.QueryRow(ctx, "SELECT email FROM users WHERE id = $1 AND LOWER(recovery_email) = LOWER($2) FOR UPDATE", userID, email).Scan(&previousEmail)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, `UPDATE users SET email = recovery_email, password = $1, recovery_email = NULL, recovery_email_verified_at = NULL, updated_at = NOW()
+		WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", err
+	}
+	return previousEmail, nil
+}
+
+// Cancel stops a pending recovery using the token sent to the primary
+// email. It returns nil when there is no pending recovery for the token.
+func (repository recovery) Cancel(ctx context.Context, cancelHash string) (*models.AccountRecovery, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var recovery models.AccountRecovery
+	err := repository.db.QueryRow(ctx, "UPDATE account_recoveries SET status = $1, updated_at = NOW() WHERE cancel_token_hash = $2 AND status = $3 RETURNING "+recoveryColumns,
+		RecoveryCancelled, cancelHash, RecoveryPending).Scan(recoveryFields(&recovery)...)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &recovery, nil
+}
@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	"api/src/database"
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type reposts struct {
+	db *pgxpool.Pool
+}
+
+func NewRepostsRepository(db *pgxpool.Pool) *reposts {
+	return &reposts{db}
+}
+
+// Create shares postID on userID's profile. It returns an empty ID when
+// the user had already reposted it.
+func (repository reposts) Create(ctx context.Context, userID string, postID string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var repostID string
+	err := repository.db.QueryRow(ctx, "INSERT INTO reposts (id, user_id, post_id, created_at) VALUES (uuid_generate_v4(), $1, $2, CURRENT_TIMESTAMP) ON CONFLICT DO NOTHING RETURNING id", userID, postID).Scan(&repostID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return repostID, nil
+}
+
+// Delete undoes a repost. It reports false when there was nothing to undo.
+func (repository reposts) Delete(ctx context.Context, userID string, postID string) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var deleted bool
+	err := database.Retry(ctx, func() error {
+		tag, err := repository.db.Exec(ctx, "DELETE FROM reposts WHERE user_id = $1 AND post_id = $2", userID, postID)
+		if err != nil {
+			return err
+		}
+		deleted = deleted || tag.RowsAffected() == 1
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
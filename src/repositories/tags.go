@@ -0,0 +1,63 @@
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type tags struct {
+	db *pgxpool.Pool
+}
+
+func NewTagsRepository(db *pgxpool.Pool) *tags {
+	return &tags{db}
+}
+
+// FindByPrefix lists the tags starting with prefix, most used first. The
+// prefix must already be normalized.
+func (repository tags) FindByPrefix(ctx context.Context, prefix string, limit int) ([]models.Tag, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+
+	var tags []models.Tag
+	err := database.Retry(ctx, func() error {
+		tags = nil
+
+		rows, err := repository.db.Query(ctx, `SELECT tags.name, COUNT(post_tags.post_id) AS total
+			FROM tags LEFT JOIN post_tags ON post_tags.tag_id = tags.id
+			WHERE tags.name LIKE $1
+			GROUP BY tags.name
+			HAVING COUNT(post_tags.post_id) > 0
+			ORDER BY total DESC, tags.name
+			LIMIT $2`, pattern, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var tag models.Tag
+			if err := rows.Scan(&tag.Name, &tag.PostsCount); err != nil {
+				return err
+			}
+			tags = append(tags, tag)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	return tags, nil
+}
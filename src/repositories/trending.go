@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"api/src/database"
+	"api/src/models"
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// maxTrendingTags is how many tags are kept per window.
+const maxTrendingTags = 100
+
+type trending struct {
+	db *pgxpool.Pool
+}
+
+func NewTrendingRepository(db *pgxpool.Pool) *trending {
+	return &trending{db}
+}
+
+// Refresh recomputes the trending tags of a window from the posts created
+// within it. Each post weighs exp(-ln 2 * age / halfLife). An author
+// contributes the weight of their newest post with the tag, scaled by
+// 1 + ln(n) for their n posts with it, so many authors beat one author
+// posting many times.
+//
+// It reports false without changing anything when another instance is
+// already refreshing the same window. Unlike other calls it runs under
+// the caller's deadline rather than the statement timeout, as the job sets
+// its own, see config.TrendingConfig.Timeout.
+func (repository trending) Refresh(ctx context.Context, window string, length time.Duration, halfLife time.Duration) (bool, error) {
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var locked bool
+	err = tx.QueryRow(ctx, "SELECT pg_try_advisory_xact_lock(hashtext('trending_tags:' || $1))", window).Scan(&locked)
+	if err != nil {
+		return false, err
+	}
+	if !locked {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM trending_tags WHERE window_name = $1", window)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(ctx, `WITH recent AS (
+			SELECT post_tags.tag_id, posts.user_id,
+				EXP(-LN(2) * EXTRACT(EPOCH FROM (LOCALTIMESTAMP - posts.created_at)) / $3) AS weight
+			FROM post_tags JOIN posts ON posts.id = post_tags.post_id
+			WHERE posts.created_at >= LOCALTIMESTAMP - make_interval(secs => $2)
+		), by_author AS (
+			SELECT tag_id, user_id, MAX(weight) AS weight, COUNT(*) AS posts
+			FROM recent GROUP BY tag_id, user_id
+		)
+		INSERT INTO trending_tags (window_name, tag_id, score, posts_count, authors_count, computed_at)
+		SELECT $1, tag_id, SUM(weight * (1 + LN(posts))) AS score, SUM(posts), COUNT(*), LOCALTIMESTAMP
+		FROM by_author
+		GROUP BY tag_id
+		ORDER BY score DESC
+		LIMIT $4`, window, length.Seconds(), halfLife.Seconds(), maxTrendingTags)
+	if err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FindByWindow returns the stored ranking of a window, best first.
+func (repository trending) FindByWindow(ctx context.Context, window string, limit int) (*models.Trending, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	result := models.Trending{Window: window}
+	err := database.Retry(ctx, func() error {
+		result.ComputedAt = nil
+		result.Tags = nil
+
+		rows, err := repository.db.Query(ctx, `SELECT tags.name, trending_tags.score, trending_tags.posts_count, trending_tags.authors_count, trending_tags.computed_at
+			FROM trending_tags JOIN tags ON tags.id = trending_tags.tag_id
+			WHERE trending_tags.window_name = $1
+			ORDER BY trending_tags.score DESC, tags.name
+			LIMIT $2`, window, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var tag models.TrendingTag
+			var computedAt time.Time
+			if err := rows.Scan(&tag.Name, &tag.Score, &tag.PostsCount, &tag.AuthorsCount, &computedAt); err != nil {
+				return err
+			}
+			result.ComputedAt = &computedAt
+			result.Tags = append(result.Tags, tag)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Tags == nil {
+		result.Tags = []models.TrendingTag{}
+	}
+
+	return &result, nil
+}
@@ -1,57 +1,137 @@
 package repositories
 
 import (
+	"api/src/config"
+	"api/src/database"
+	"api/src/events"
 	"api/src/models"
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type users struct {
-	db *pgx.Conn
+	db *pgxpool.Pool
 }
 
-func NewUsersRepository(db *pgx.Conn) *users {
+func NewUsersRepository(db *pgxpool.Pool) *users {
 	return &users{db}
 }
 
-func (repository users) Create(user models.User) (string, error) {
-	tx, err := repository.db.Begin(context.Background())
+// Profile image URLs are built from these. Storage keys double as paths
+// under imageURLPrefix, see controllers.AvatarGet.
+const (
+	imageURLPrefix     = config.BasePath + "/"
+	identiconURLPrefix = config.BasePath + "/users/"
+	identiconURLSuffix = "/identicon"
+)
+
+// avatarURL is the SQL for where the avatar of the users row u is served,
+// which is its generated identicon until one is uploaded.
+func avatarURL(u string) string {
+	return fmt.Sprintf("COALESCE('%[2]s' || %[1]s.avatar_key, '%[3]s' || %[1]s.id || '%[4]s')",
+		u, imageURLPrefix, identiconURLPrefix, identiconURLSuffix)
+}
+
+// bannerURL is avatarURL for banners, which have no fallback.
+func bannerURL(u string) string {
+	return fmt.Sprintf("'%s' || %s.banner_key", imageURLPrefix, u)
+}
+
+// userColumns and userFields read the users row u, without its password.
+func userColumns(u string) string {
+	return fmt.Sprintf("%[1]s.id, %[1]s.name, %[1]s.email, %[1]s.created_at, %[2]s, %[3]s, %[1]s.handle, %[1]s.bio, %[1]s.location, %[1]s.website, %[1]s.pronouns",
+		u, avatarURL(u), bannerURL(u))
+}
+
+func userFields(user *models.User) []interface{} {
+	return []interface{}{&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.AvatarURL, &user.BannerURL,
+		&user.Handle, &user.Bio, &user.Location, &user.Website, &user.Pronouns}
+}
+
+func (repository users) Create(ctx context.Context, user models.User) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
 	if err != nil {
-		log.Fatal(err)
+		return "", err
 	}
 
 	var userId string
-	err = tx.QueryRow(context.Background(), "INSERT INTO users (id, name, email, password, created_at) VALUES (uuid_generate_v4(), $1, $2, $3, CURRENT_TIMESTAMP) RETURNING id", user.Name, user.Email, user.Password).Scan(&userId)
+	err = tx.QueryRow(ctx, `INSERT INTO users (id, name, email, password, bio, location, website, pronouns, created_at)
+		VALUES (uuid_generate_v4(), $1, $2, $3, $4, $5, $6, $7, CURRENT_TIMESTAMP) RETURNING id`,
+		user.Name, user.Email, user.Password, user.Bio, user.Location, user.Website, user.Pronouns).Scan(&userId)
 	if err != nil {
-		tx.Rollback(context.Background())
+		tx.Rollback(ctx)
 		return "", err
 	}
 
-	err = tx.Commit(context.Background())
+	if user.Handle != nil {
+		if err := lockHandles(ctx, tx, *user.Handle); err != nil {
+			tx.Rollback(ctx)
+			return "", err
+		}
+
+		if err := claimHandle(ctx, tx, userId, *user.Handle); err != nil {
+			tx.Rollback(ctx)
+			return "", err
+		}
+
+		_, err = tx.Exec(ctx, "UPDATE users SET handle = $2 WHERE id = $1", userId, *user.Handle)
+		if err != nil {
+			tx.Rollback(ctx)
+			return "", err
+		}
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return "", err
 	}
+
+	events.Publish(ctx, events.New(events.UserCreated, userId, userId, map[string]string{"id": userId, "name": user.Name}))
 	return userId, nil
 }
 
-func (repository users) FindById(id string) (*models.User, error) {
+func (repository users) FindById(ctx context.Context, id string) (*models.User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var user models.User
-	err := repository.db.QueryRow(context.Background(), "SELECT id, name, email, created_at FROM users WHERE id = $1", id).Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt)
+	var followersCount, followingCount int
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, `SELECT `+userColumns("users")+`,
+			(SELECT COUNT(*) FROM followers WHERE followed_id = users.id),
+			(SELECT COUNT(*) FROM followers WHERE follower_id = users.id)
+			FROM users WHERE id = $1`, id).Scan(append(userFields(&user), &followersCount, &followingCount)...)
+	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	user.FollowersCount = &followersCount
+	user.FollowingCount = &followingCount
 	return &user, nil
 }
 
-func (repository users) FindByEmail(email string) (*models.User, error) {
+func (repository users) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	var user models.User
-	err := repository.db.QueryRow(context.Background(), "SELECT id, name, email, password, created_at FROM users WHERE email = $1", email).Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.CreatedAt)
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT "+userColumns("users")+", password FROM users WHERE email = $1", email).
+			Scan(append(userFields(&user), &user.Password)...)
+	})
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -61,8 +141,11 @@ func (repository users) FindByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (repository users) FindMany(limit int, offset int, filters map[string]interface{}) ([]models.User, error) {
-	query := "SELECT id, name, email, created_at FROM users WHERE 1=1"
+func (repository users) FindMany(ctx context.Context, limit int, offset int, filters map[string]interface{}) ([]models.User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	query := "SELECT " + userColumns("users") + " FROM users WHERE 1=1"
 	args := []interface{}{}
 	argID := 1
 
@@ -75,29 +158,52 @@ func (repository users) FindMany(limit int, offset int, filters map[string]inter
 	query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", argID, argID+1)
 	args = append(args, limit, offset)
 
-	rows, err := repository.db.Query(context.Background(), query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
 	var users []models.User
-	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt); err != nil {
-			return nil, err
+	err := database.Retry(ctx, func() error {
+		users = nil
+
+		rows, err := repository.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var user models.User
+			if err := rows.Scan(userFields(&user)...); err != nil {
+				return err
+			}
+			users = append(users, user)
 		}
-		users = append(users, user)
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return users, nil
 }
 
-func (repository users) Update(id string, fields map[string]interface{}) (*models.User, error) {
+// updatableUserColumns are the columns Update may set. The rest are owned
+// by the server or by endpoints that check their values, such as
+// /me/profile and /me/time-zone.
+var updatableUserColumns = map[string]bool{"name": true, "password": true}
+
+func (repository users) Update(ctx context.Context, id string, fields map[string]interface{}) (*models.User, error) {
 	if len(fields) == 0 {
 		return nil, nil
 	}
 
+	for key := range fields {
+		if !updatableUserColumns[key] {
+			return nil, fmt.Errorf("%w: %s", ErrFieldNotAllowed, key)
+		}
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
 	query := "UPDATE users SET"
 	args := []interface{}{}
 	argID := 1
@@ -113,25 +219,399 @@ func (repository users) Update(id string, fields map[string]interface{}) (*model
 
 	query += fmt.Sprintf(", updated_at = NOW()")
 
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, name, email, created_at", argID)
+	query += fmt.Sprintf(" WHERE id = $%d RETURNING %s", argID, userColumns("users"))
 	args = append(args, id)
 
 	var updatedUser models.User
-	err := repository.db.QueryRow(context.Background(), query, args...).Scan(&updatedUser.ID, &updatedUser.Name, &updatedUser.Email, &updatedUser.CreatedAt)
+	err := repository.db.QueryRow(ctx, query, args...).Scan(userFields(&updatedUser)...)
 	if err != nil {
 		return nil, err
 	}
 
+	events.Publish(ctx, events.New(events.UserUpdated, id, id, updatedUser.Summary()))
 	return &updatedUser, nil
 }
 
-func (repository users) Delete(id string) (string, error) {
+func (repository users) Delete(ctx context.Context, id string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, "DELETE FROM followers WHERE follower_id = $1 OR followed_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	// Replies to these comments go with them, see fk_comments_parent_id.
+	_, err = tx.Exec(ctx, "DELETE FROM comments WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM reactions WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM reposts WHERE user_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM bookmarks WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM mentions WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM post_tags WHERE post_id IN (SELECT id FROM posts WHERE user_id = $1)", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM notifications WHERE user_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM recovery_email_verifications WHERE user_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM account_recoveries WHERE user_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM messages WHERE sender_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM conversation_members WHERE user_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = tx.Exec(ctx, "DELETE FROM handle_redirects WHERE user_id = $1", id)
+	if err != nil {
+		return "", err
+	}
+
+	// Orphaned uploads are removed by the media garbage collector.
+	_, err = tx.Exec(ctx, "UPDATE media SET user_id = NULL, post_id = NULL WHERE user_id = $1 OR post_id IN (SELECT id FROM posts WHERE user_id = $1)", id)
+	if err != nil {
+		return "", err
+	}
+
+	// Posts go last, once nothing references them. As with posts.Delete,
+	// quotes and reposts of them by others become tombstones.
+	var postIDs []string
+	rows, err := tx.Query(ctx, "DELETE FROM posts WHERE user_id = $1 RETURNING id", id)
+	if err != nil {
+		return "", err
+	}
+	for rows.Next() {
+		var postID string
+		if err := rows.Scan(&postID); err != nil {
+			rows.Close()
+			return "", err
+		}
+		postIDs = append(postIDs, postID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
 	query := "DELETE FROM users WHERE id = $1 RETURNING id"
 	var deletedUser string
-	err := repository.db.QueryRow(context.Background(), query, id).Scan(&deletedUser)
+	err = tx.QueryRow(ctx, query, id).Scan(&deletedUser)
+	if err != nil {
+		return "", err
+	}
+
+	err = tx.Commit(ctx)
 	if err != nil {
 		return "", err
 	}
 
+	for _, postID := range postIDs {
+		events.Publish(ctx, events.New(events.PostDeleted, postID, deletedUser, map[string]string{"id": postID}))
+	}
+	events.Publish(ctx, events.New(events.UserDeleted, deletedUser, deletedUser, map[string]string{"id": deletedUser}))
 	return deletedUser, nil
 }
+
+// FindTimeZone returns the IANA time zone of a user, or an empty string
+// if there is no such user.
+func (repository users) FindTimeZone(ctx context.Context, id string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var timeZone string
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT time_zone FROM users WHERE id = $1", id).Scan(&timeZone)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return timeZone, nil
+}
+
+func (repository users) SetTimeZone(ctx context.Context, id string, timeZone string) error {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	_, err := repository.db.Exec(ctx, "UPDATE users SET time_zone = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1", id, timeZone)
+	return err
+}
+
+// Profile image columns for SetImage.
+const (
+	ImageAvatar = "avatar_key"
+	ImageBanner = "banner_key"
+)
+
+// SetImage stores the storage key of a user's avatar or banner, or clears
+// it when key is nil. It returns the key it replaced, and false if there
+// is no such user.
+func (repository users) SetImage(ctx context.Context, id string, column string, key *string) (*string, bool, error) {
+	if column != ImageAvatar && column != ImageBanner {
+		return nil, false, fmt.Errorf("unknown image column %q", column)
+	}
+
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var previous *string
+	var summary models.UserSummary
+	query := fmt.Sprintf(`UPDATE users SET %[1]s = $2, updated_at = CURRENT_TIMESTAMP
+		FROM (SELECT %[1]s FROM users WHERE id = $1 FOR UPDATE) old
+		WHERE users.id = $1 RETURNING old.%[1]s, users.id, users.name, users.handle, %[2]s`, column, avatarURL("users"))
+	err := repository.db.QueryRow(ctx, query, id, key).Scan(&previous, &summary.ID, &summary.Name, &summary.Handle, &summary.AvatarURL)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, false, nil
+		}
+		return nil, false, err
+	}
+
+	events.Publish(ctx, events.New(events.UserUpdated, id, id, summary))
+	return previous, true, nil
+}
+
+// FindImageKeys returns the storage keys of a user's avatar and banner,
+// whichever are set.
+func (repository users) FindImageKeys(ctx context.Context, id string) ([]string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var avatarKey, bannerKey *string
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, "SELECT avatar_key, banner_key FROM users WHERE id = $1", id).Scan(&avatarKey, &bannerKey)
+	})
+	if err != nil && err != pgx.ErrNoRows {
+		return nil, err
+	}
+
+	keys := []string{}
+	for _, key := range []*string{avatarKey, bannerKey} {
+		if key != nil {
+			keys = append(keys, *key)
+		}
+	}
+	return keys, nil
+}
+
+// ErrHandleTaken is returned when another user has a handle, or had it
+// too recently for anyone else to take it.
+var ErrHandleTaken = errors.New("handle is taken")
+
+// Profile holds the profile fields to change. Nil fields are left as
+// they are.
+type Profile struct {
+	Handle   *string
+	Bio      *string
+	Location *string
+	Website  *string
+	Pronouns *string
+}
+
+// UpdateProfile changes the profile of a user, reporting false if there
+// is no such user. A replaced handle keeps redirecting to the user, and
+// stays reserved for them, for redirectTTL.
+func (repository users) UpdateProfile(ctx context.Context, id string, profile Profile, redirectTTL time.Duration) (bool, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	tx, err := repository.db.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var current *string
+	err = tx.QueryRow(ctx, "SELECT handle FROM users WHERE id = $1 FOR UPDATE", id).Scan(&current)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+
+	// Changing only the case of a handle keeps it, so there is nothing to
+	// claim or redirect.
+	if profile.Handle != nil && (current == nil || !strings.EqualFold(*current, *profile.Handle)) {
+		locked := []string{*profile.Handle}
+		if current != nil {
+			locked = append(locked, *current)
+		}
+		if err := lockHandles(ctx, tx, locked...); err != nil {
+			return false, err
+		}
+
+		if err := claimHandle(ctx, tx, id, *profile.Handle); err != nil {
+			return false, err
+		}
+
+		if current != nil {
+			_, err = tx.Exec(ctx, `INSERT INTO handle_redirects (handle, user_id, expires_at) VALUES (LOWER($1), $2, LOCALTIMESTAMP + make_interval(secs => $3))
+				ON CONFLICT (handle) DO UPDATE SET user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at`, *current, id, redirectTTL.Seconds())
+			if err != nil {
+				return false, err
+			}
+		}
+	}
+
+	query := "UPDATE users SET updated_at = CURRENT_TIMESTAMP"
+	args := []interface{}{id}
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"handle", profile.Handle},
+		{"bio", profile.Bio},
+		{"location", profile.Location},
+		{"website", profile.Website},
+		{"pronouns", profile.Pronouns},
+	} {
+		if field.value != nil {
+			args = append(args, *field.value)
+			query += fmt.Sprintf(", %s = $%d", field.column, len(args))
+		}
+	}
+	query += " WHERE id = $1 RETURNING " + userColumns("users")
+
+	var updatedUser models.User
+	if err := tx.QueryRow(ctx, query, args...).Scan(userFields(&updatedUser)...); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+
+	events.Publish(ctx, events.New(events.UserUpdated, id, id, updatedUser.Summary()))
+	return true, nil
+}
+
+// lockHandles serializes changes involving the same handles until tx
+// ends. Locks are taken in a fixed order so that two users swapping
+// handles cannot deadlock.
+func lockHandles(ctx context.Context, tx pgx.Tx, handles ...string) error {
+	keys := make([]string, len(handles))
+	for i, handle := range handles {
+		keys[i] = strings.ToLower(handle)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock(hashtext('handle:' || $1))", key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimHandle returns ErrHandleTaken unless userID may take handle. Users
+// may take back their own old handles. The handle must be locked with
+// lockHandles first.
+func claimHandle(ctx context.Context, tx pgx.Tx, userID string, handle string) error {
+	_, err := tx.Exec(ctx, "DELETE FROM handle_redirects WHERE handle = LOWER($1) AND (user_id = $2 OR expires_at <= LOCALTIMESTAMP)", handle, userID)
+	if err != nil {
+		return err
+	}
+
+	var taken bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(handle) = LOWER($1) AND id <> $2)
+		OR EXISTS (SELECT 1 FROM handle_redirects WHERE handle = LOWER($1))`, handle, userID).Scan(&taken)
+	if err != nil {
+		return err
+	}
+
+	if taken {
+		return ErrHandleTaken
+	}
+	return nil
+}
+
+// FindByHandle returns the user with a handle, compared
+// case-insensitively, or nil if there is none.
+func (repository users) FindByHandle(ctx context.Context, handle string) (*models.User, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var user models.User
+	var followersCount, followingCount int
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, `SELECT `+userColumns("users")+`,
+			(SELECT COUNT(*) FROM followers WHERE followed_id = users.id),
+			(SELECT COUNT(*) FROM followers WHERE follower_id = users.id)
+			FROM users WHERE LOWER(handle) = LOWER($1)`, handle).Scan(append(userFields(&user), &followersCount, &followingCount)...)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	user.FollowersCount = &followersCount
+	user.FollowingCount = &followingCount
+	return &user, nil
+}
+
+// FindHandleRedirect returns the current handle of the user who recently
+// gave up handle, or an empty string if nobody did.
+func (repository users) FindHandleRedirect(ctx context.Context, handle string) (string, error) {
+	ctx, cancel := database.WithTimeout(ctx)
+	defer cancel()
+
+	var current string
+	err := database.Retry(ctx, func() error {
+		return repository.db.QueryRow(ctx, `SELECT u.handle FROM handle_redirects r JOIN users u ON u.id = r.user_id
+			WHERE r.handle = LOWER($1) AND r.expires_at > LOCALTIMESTAMP AND u.handle IS NOT NULL`, handle).Scan(&current)
+	})
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return current, nil
+}
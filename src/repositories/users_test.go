@@ -0,0 +1,40 @@
+package repositories_test
+
+import (
+	"api/src/controllers"
+	"api/src/repositories"
+	"api/src/router"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestProfileImageURLsResolve(t *testing.T) {
+	const id = "4f8e2a1c-6b3d-4e5f-9a7b-8c9d0e1f2a3b"
+
+	tests := []struct {
+		name    string
+		url     string
+		handler func(http.ResponseWriter, *http.Request)
+	}{
+		{"avatar", repositories.ImageURL("avatars/" + id + ".jpg"), controllers.AvatarGet},
+		{"banner", repositories.ImageURL("banners/" + id + ".png"), controllers.BannerGet},
+		{"identicon", repositories.IdenticonURL(id), controllers.IdenticonGet},
+	}
+
+	r := router.GenerateRouter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var match mux.RouteMatch
+			if !r.Match(httptest.NewRequest(http.MethodGet, tt.url, nil), &match) {
+				t.Fatalf("%s matches no route", tt.url)
+			}
+			if reflect.ValueOf(match.Route.GetHandler()).Pointer() != reflect.ValueOf(tt.handler).Pointer() {
+				t.Errorf("%s is routed to the wrong handler", tt.url)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"api/src/config"
+	"api/src/middlewares"
 	"api/src/router/routes"
 
 	"github.com/gorilla/mux"
@@ -9,12 +11,15 @@ import (
 
 func GenerateRouter() *mux.Router {
 	r := mux.NewRouter()
-	r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"),
-		httpSwagger.DeepLinking(true),
-		httpSwagger.DocExpansion("none"),
-	))
-	apiRouter := r.PathPrefix("/api").Subrouter()
+	r.Use(middlewares.RequestID)
+	if config.Get().Features.Swagger {
+		r.PathPrefix("/swagger/").Handler(httpSwagger.Handler(
+			httpSwagger.URL("/swagger/doc.json"),
+			httpSwagger.DeepLinking(true),
+			httpSwagger.DocExpansion("none"),
+		))
+	}
+	apiRouter := r.PathPrefix(config.BasePath).Subrouter()
 	routes.ConfigRoutes(apiRouter)
 	return r
 }
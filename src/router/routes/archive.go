@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var archiveRoutes = []Route{
+	{
+		Uri:       "/users/{id}/archive",
+		Method:    http.MethodGet,
+		Function:  controllers.ArchiveGet,
+		Protected: false,
+	},
+	{
+		Uri:       "/users/{id}/archive/{year}/{month}",
+		Method:    http.MethodGet,
+		Function:  controllers.ArchiveGetMonth,
+		Protected: false,
+	},
+	{
+		Uri:       "/me/on-this-day",
+		Method:    http.MethodGet,
+		Function:  controllers.OnThisDay,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/time-zone",
+		Method:    http.MethodGet,
+		Function:  controllers.TimeZoneGet,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/time-zone",
+		Method:    http.MethodPut,
+		Function:  controllers.TimeZoneSet,
+		Protected: true,
+	},
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var avatarRoutes = []Route{
+	{
+		Uri:       "/me/avatar",
+		Method:    http.MethodPut,
+		Function:  controllers.AvatarUpload,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/avatar",
+		Method:    http.MethodDelete,
+		Function:  controllers.AvatarDelete,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/banner",
+		Method:    http.MethodPut,
+		Function:  controllers.BannerUpload,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/banner",
+		Method:    http.MethodDelete,
+		Function:  controllers.BannerDelete,
+		Protected: true,
+	},
+	{
+		Uri:       "/avatars/{name}",
+		Method:    http.MethodGet,
+		Function:  controllers.AvatarGet,
+		Protected: false,
+	},
+	{
+		Uri:       "/banners/{name}",
+		Method:    http.MethodGet,
+		Function:  controllers.BannerGet,
+		Protected: false,
+	},
+	{
+		Uri:       "/users/{id}/identicon",
+		Method:    http.MethodGet,
+		Function:  controllers.IdenticonGet,
+		Protected: false,
+	},
+}
@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var bookmarkRoutes = []Route{
+	{
+		Uri:       "/posts/{id}/bookmark",
+		Method:    http.MethodPut,
+		Function:  controllers.BookmarkAdd,
+		Protected: true,
+	},
+	{
+		Uri:       "/posts/{id}/bookmark",
+		Method:    http.MethodDelete,
+		Function:  controllers.BookmarkRemove,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/bookmarks",
+		Method:    http.MethodGet,
+		Function:  controllers.BookmarkGetAll,
+		Protected: true,
+	},
+}
@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var commentRoutes = []Route{
+	{
+		Uri:       "/posts/{id}/comments",
+		Method:    http.MethodPost,
+		Function:  controllers.CommentCreate,
+		Protected: true,
+	},
+	{
+		Uri:       "/posts/{id}/comments",
+		Method:    http.MethodGet,
+		Function:  controllers.CommentGetAll,
+		Protected: false,
+	},
+	{
+		Uri:       "/posts/{id}/comments/{commentId}",
+		Method:    http.MethodPut,
+		Function:  controllers.CommentUpdate,
+		Protected: true,
+	},
+	{
+		Uri:       "/posts/{id}/comments/{commentId}",
+		Method:    http.MethodDelete,
+		Function:  controllers.CommentDelete,
+		Protected: true,
+	},
+}
@@ -0,0 +1,81 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var conversationRoutes = []Route{
+	{
+		Uri:       "/conversations",
+		Method:    http.MethodPost,
+		Function:  controllers.ConversationCreate,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/conversations",
+		Method:    http.MethodGet,
+		Function:  controllers.ConversationGetAll,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/conversations/unread-count",
+		Method:    http.MethodGet,
+		Function:  controllers.ConversationUnreadCount,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}",
+		Method:    http.MethodGet,
+		Function:  controllers.ConversationGet,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}/messages",
+		Method:    http.MethodGet,
+		Function:  controllers.MessageGetAll,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}/messages",
+		Method:    http.MethodPost,
+		Function:  controllers.MessageCreate,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}/read",
+		Method:    http.MethodPut,
+		Function:  controllers.ConversationMarkRead,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}/mute",
+		Method:    http.MethodPut,
+		Function:  controllers.ConversationMute,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}/mute",
+		Method:    http.MethodDelete,
+		Function:  controllers.ConversationUnmute,
+		Protected: true,
+	},
+	{
+		Uri:       "/conversations/{id}/leave",
+		Method:    http.MethodPost,
+		Function:  controllers.ConversationLeave,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/message-settings",
+		Method:    http.MethodGet,
+		Function:  controllers.MessageSettingsGet,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/message-settings",
+		Method:    http.MethodPut,
+		Function:  controllers.MessageSettingsSet,
+		Protected: true,
+	},
+}
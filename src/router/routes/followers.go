@@ -0,0 +1,33 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var followerRoutes = []Route{
+	{
+		Uri:       "/users/{id}/follow",
+		Method:    http.MethodPost,
+		Function:  controllers.UserFollow,
+		Protected: true,
+	},
+	{
+		Uri:       "/users/{id}/follow",
+		Method:    http.MethodDelete,
+		Function:  controllers.UserUnfollow,
+		Protected: true,
+	},
+	{
+		Uri:       "/users/{id}/followers",
+		Method:    http.MethodGet,
+		Function:  controllers.UserFollowers,
+		Protected: false,
+	},
+	{
+		Uri:       "/users/{id}/following",
+		Method:    http.MethodGet,
+		Function:  controllers.UserFollowing,
+		Protected: false,
+	},
+}
@@ -9,7 +9,19 @@ var healthRoutes = []Route{
 	{
 		Uri:       "/health",
 		Method:    http.MethodGet,
-		Function:  controllers.HealthCheck,
+		Function:  controllers.HealthReady,
+		Protected: false,
+	},
+	{
+		Uri:       "/health/live",
+		Method:    http.MethodGet,
+		Function:  controllers.HealthLive,
+		Protected: false,
+	},
+	{
+		Uri:       "/health/ready",
+		Method:    http.MethodGet,
+		Function:  controllers.HealthReady,
 		Protected: false,
 	},
 }
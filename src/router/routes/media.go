@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var mediaRoutes = []Route{
+	{
+		Uri:       "/media",
+		Method:    http.MethodPost,
+		Function:  controllers.MediaUpload,
+		Protected: true,
+	},
+	{
+		Uri:       "/media/{id}",
+		Method:    http.MethodGet,
+		Function:  controllers.MediaGet,
+		Protected: false,
+	},
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var mentionRoutes = []Route{
+	{
+		Uri:       "/me/mentions",
+		Method:    http.MethodGet,
+		Function:  controllers.MentionGetAll,
+		Protected: true,
+	},
+}
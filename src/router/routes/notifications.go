@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var notificationRoutes = []Route{
+	{
+		Uri:       "/me/notifications",
+		Method:    http.MethodGet,
+		Function:  controllers.NotificationGetAll,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/notifications/unread-count",
+		Method:    http.MethodGet,
+		Function:  controllers.NotificationUnreadCount,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/notifications/read",
+		Method:    http.MethodPut,
+		Function:  controllers.NotificationMarkAllRead,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/notifications/{id}/read",
+		Method:    http.MethodPut,
+		Function:  controllers.NotificationMarkRead,
+		Protected: true,
+	},
+	{
+		Uri:       "/notifications/broadcast",
+		Method:    http.MethodPost,
+		Function:  controllers.NotificationBroadcast,
+		Protected: true,
+	},
+}
@@ -12,12 +12,24 @@ var postRoutes = []Route{
 		Function:  controllers.PostCreate,
 		Protected: true,
 	},
+	{
+		Uri:       "/posts",
+		Method:    http.MethodGet,
+		Function:  controllers.PostGetTimeline,
+		Protected: false,
+	},
 	{
 		Uri:       "/posts-by-user",
 		Method:    http.MethodGet,
 		Function:  controllers.PostGetAllByUserId,
 		Protected: true,
 	},
+	{
+		Uri:       "/users/{id}/posts",
+		Method:    http.MethodGet,
+		Function:  controllers.PostGetAllByAuthor,
+		Protected: false,
+	},
 	{
 		Uri:       "/posts/{id}",
 		Method:    http.MethodGet,
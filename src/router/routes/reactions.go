@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var reactionRoutes = []Route{
+	{
+		Uri:       "/posts/{id}/reactions/{type}",
+		Method:    http.MethodPut,
+		Function:  controllers.ReactionAdd,
+		Protected: true,
+	},
+	{
+		Uri:       "/posts/{id}/reactions/{type}",
+		Method:    http.MethodDelete,
+		Function:  controllers.ReactionRemove,
+		Protected: true,
+	},
+	{
+		Uri:       "/posts/{id}/reactions",
+		Method:    http.MethodGet,
+		Function:  controllers.ReactionGetAll,
+		Protected: false,
+	},
+}
@@ -0,0 +1,51 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var recoveryRoutes = []Route{
+	{
+		Uri:       "/me/recovery-email",
+		Method:    http.MethodGet,
+		Function:  controllers.RecoveryEmailGet,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/recovery-email",
+		Method:    http.MethodPut,
+		Function:  controllers.RecoveryEmailSet,
+		Protected: true,
+	},
+	{
+		Uri:       "/me/recovery-email",
+		Method:    http.MethodDelete,
+		Function:  controllers.RecoveryEmailRemove,
+		Protected: true,
+	},
+	{
+		Uri:       "/recovery-email/verify",
+		Method:    http.MethodPost,
+		Function:  controllers.RecoveryEmailVerify,
+		Protected: false,
+	},
+	{
+		Uri:       "/account-recovery",
+		Method:    http.MethodPost,
+		Function:  controllers.AccountRecoveryStart,
+		Protected: false,
+	},
+	{
+		Uri:       "/account-recovery/confirm",
+		Method:    http.MethodPost,
+		Function:  controllers.AccountRecoveryConfirm,
+		Protected: false,
+	},
+	{
+		Uri:       "/account-recovery/cancel",
+		Method:    http.MethodPost,
+		Function:  controllers.AccountRecoveryCancel,
+		Protected: false,
+	},
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var repostRoutes = []Route{
+	{
+		Uri:       "/posts/{id}/repost",
+		Method:    http.MethodPost,
+		Function:  controllers.RepostCreate,
+		Protected: true,
+	},
+	{
+		Uri:       "/posts/{id}/repost",
+		Method:    http.MethodDelete,
+		Function:  controllers.RepostDelete,
+		Protected: true,
+	},
+}
@@ -28,6 +28,22 @@ func ConfigRoutes(r *mux.Router) *mux.Router {
 	routes = append(routes, authRoutes...)
 	routes = append(routes, postRoutes...)
 	routes = append(routes, healthRoutes...)
+	routes = append(routes, followerRoutes...)
+	routes = append(routes, commentRoutes...)
+	routes = append(routes, reactionRoutes...)
+	routes = append(routes, repostRoutes...)
+	routes = append(routes, bookmarkRoutes...)
+	routes = append(routes, tagRoutes...)
+	routes = append(routes, trendingRoutes...)
+	routes = append(routes, mentionRoutes...)
+	routes = append(routes, notificationRoutes...)
+	routes = append(routes, recoveryRoutes...)
+	routes = append(routes, streamRoutes...)
+	routes = append(routes, socketRoutes...)
+	routes = append(routes, conversationRoutes...)
+	routes = append(routes, mediaRoutes...)
+	routes = append(routes, archiveRoutes...)
+	routes = append(routes, avatarRoutes...)
 
 	for _, route := range routes {
 		if route.Protected {
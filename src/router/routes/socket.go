@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+// Like the stream, the WebSocket authenticates by itself because browsers
+// cannot send the Authorization header when opening one.
+var socketRoutes = []Route{
+	{
+		Uri:       "/ws",
+		Method:    http.MethodGet,
+		Function:  controllers.Socket,
+		Protected: false,
+	},
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+// The stream authenticates by itself because browsers' EventSource cannot
+// send the Authorization header.
+var streamRoutes = []Route{
+	{
+		Uri:       "/stream",
+		Method:    http.MethodGet,
+		Function:  controllers.Stream,
+		Protected: false,
+	},
+}
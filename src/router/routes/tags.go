@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var tagRoutes = []Route{
+	{
+		Uri:       "/tags",
+		Method:    http.MethodGet,
+		Function:  controllers.TagAutocomplete,
+		Protected: false,
+	},
+	{
+		Uri:       "/tags/{tag}/posts",
+		Method:    http.MethodGet,
+		Function:  controllers.TagGetPosts,
+		Protected: false,
+	},
+}
@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"api/src/controllers"
+	"net/http"
+)
+
+var trendingRoutes = []Route{
+	{
+		Uri:       "/trending/tags",
+		Method:    http.MethodGet,
+		Function:  controllers.TrendingGetTags,
+		Protected: false,
+	},
+	{
+		Uri:       "/trending/tags/refresh",
+		Method:    http.MethodPost,
+		Function:  controllers.TrendingRefresh,
+		Protected: true,
+	},
+}
@@ -18,6 +18,20 @@ var userRoutes = []Route{
 		Function:  controllers.UserGetAll,
 		Protected: false,
 	},
+	{
+		// Before the /users/{id}/... routes, which would otherwise take
+		// "by-handle" for an ID.
+		Uri:       "/users/by-handle/{handle}",
+		Method:    http.MethodGet,
+		Function:  controllers.UserGetByHandle,
+		Protected: false,
+	},
+	{
+		Uri:       "/me/profile",
+		Method:    http.MethodPut,
+		Function:  controllers.ProfileUpdate,
+		Protected: true,
+	},
 	{
 		Uri:       "/users/{id}",
 		Method:    http.MethodGet,
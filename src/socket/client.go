@@ -0,0 +1,214 @@
+package socket
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// writeWait bounds writing one message to a client.
+	writeWait = 10 * time.Second
+	// maxRequestSize bounds messages read from clients.
+	maxRequestSize = 4096
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+type client struct {
+	gateway *Gateway
+	conn    *websocket.Conn
+	userID  string
+	send    chan []byte
+
+	// topics is guarded by gateway.mu.
+	topics map[string]struct{}
+
+	done     chan struct{}
+	stopOnce sync.Once
+	closeMu  sync.Mutex
+	close    []byte
+}
+
+// Serve upgrades the request to a WebSocket for userID, who must already
+// be authenticated, and handles it until the client goes away.
+func (g *Gateway) Serve(w http.ResponseWriter, r *http.Request, userID string) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error.
+		return
+	}
+
+	c := &client{
+		gateway: g,
+		conn:    conn,
+		userID:  userID,
+		send:    make(chan []byte, g.cfg.SendBuffer),
+		topics:  make(map[string]struct{}),
+		done:    make(chan struct{}),
+	}
+
+	if !g.add(c) {
+		c.closeGoingAway()
+		c.writeClose()
+		return
+	}
+
+	go c.writeLoop()
+	c.readLoop(r)
+	g.disconnect(c)
+}
+
+// enqueue queues message for sending. It reports false when the client's
+// buffer is full.
+func (c *client) enqueue(message []byte) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+	}
+
+	select {
+	case c.send <- message:
+		return true
+	default:
+		return false
+	}
+}
+
+func (c *client) reply(response Response) {
+	message, err := json.Marshal(response)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode WebSocket reply")
+		return
+	}
+
+	if !c.enqueue(message) {
+		c.gateway.mu.Lock()
+		c.gateway.evict(c)
+		c.gateway.mu.Unlock()
+	}
+}
+
+func (c *client) readLoop(r *http.Request) {
+	pongWait := 2 * c.gateway.cfg.PingInterval
+
+	c.conn.SetReadLimit(maxRequestSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request Request
+		if err := json.Unmarshal(data, &request); err != nil {
+			c.reply(Response{Type: TypeError, Error: "invalid message"})
+			continue
+		}
+
+		switch request.Type {
+		case TypeSubscribe:
+			if err := authorize(r.Context(), c.userID, request.Topic); err != nil {
+				c.reply(Response{Type: TypeError, ID: request.ID, Topic: request.Topic, Error: subscribeError(err)})
+				continue
+			}
+			if !c.gateway.subscribe(c, request.Topic) {
+				c.reply(Response{Type: TypeError, ID: request.ID, Topic: request.Topic, Error: "too many topics"})
+				continue
+			}
+			c.reply(Response{Type: TypeAck, ID: request.ID, Topic: request.Topic})
+		case TypeUnsubscribe:
+			c.gateway.unsubscribe(c, request.Topic)
+			c.reply(Response{Type: TypeAck, ID: request.ID, Topic: request.Topic})
+		case TypePing:
+			c.reply(Response{Type: TypePong, ID: request.ID})
+		default:
+			c.reply(Response{Type: TypeError, ID: request.ID, Error: "unknown message type"})
+		}
+	}
+}
+
+func subscribeError(err error) string {
+	switch {
+	case errors.Is(err, errUnknownTopic):
+		return "unknown topic"
+	case errors.Is(err, errNotFound):
+		return "not found"
+	default:
+		log.Error().Err(err).Msg("Failed to authorize WebSocket subscription")
+		return "internal error"
+	}
+}
+
+func (c *client) writeLoop() {
+	ping := time.NewTicker(c.gateway.cfg.PingInterval)
+	defer ping.Stop()
+	defer c.conn.Close()
+
+	for {
+		select {
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				c.gateway.disconnect(c)
+				return
+			}
+		case <-ping.C:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				c.gateway.disconnect(c)
+				return
+			}
+		case <-c.done:
+			c.writeClose()
+			return
+		}
+	}
+}
+
+// writeClose sends the close frame chosen when the client was stopped.
+func (c *client) writeClose() {
+	c.closeMu.Lock()
+	message := c.close
+	c.closeMu.Unlock()
+
+	if message == nil {
+		message = websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	}
+	c.conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(writeWait))
+	c.conn.Close()
+}
+
+func (c *client) stop() {
+	c.stopOnce.Do(func() { close(c.done) })
+}
+
+func (c *client) closeWith(code int, text string) {
+	c.closeMu.Lock()
+	if c.close == nil {
+		c.close = websocket.FormatCloseMessage(code, text)
+	}
+	c.closeMu.Unlock()
+	c.stop()
+}
+
+// closeSlow disconnects a client that fell behind. It may reconnect.
+func (c *client) closeSlow() {
+	c.closeWith(websocket.CloseTryAgainLater, "slow consumer")
+}
+
+func (c *client) closeGoingAway() {
+	c.closeWith(websocket.CloseGoingAway, "server shutting down")
+}
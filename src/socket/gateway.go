@@ -0,0 +1,165 @@
+// Package socket is a WebSocket gateway where clients subscribe to topics
+// and receive the events published on them.
+package socket
+
+import (
+	"api/src/config"
+	"encoding/json"
+	"sync"
+)
+
+// Gateway routes published events to the clients subscribed to their
+// topic.
+type Gateway struct {
+	cfg config.SocketConfig
+
+	mu      sync.Mutex
+	clients map[*client]struct{}
+	topics  map[string]map[*client]struct{}
+	closed  bool
+}
+
+func NewGateway(cfg config.SocketConfig) *Gateway {
+	return &Gateway{
+		cfg:     cfg,
+		clients: make(map[*client]struct{}),
+		topics:  make(map[string]map[*client]struct{}),
+	}
+}
+
+// Publish sends an event to every client subscribed to topic. Clients
+// whose send buffer is full are evicted.
+func (g *Gateway) Publish(topic string, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	message, err := json.Marshal(Response{Type: TypeEvent, Topic: topic, Event: event, Data: payload})
+	if err != nil {
+		return err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for c := range g.topics[topic] {
+		if !c.enqueue(message) {
+			g.evict(c)
+		}
+	}
+
+	return nil
+}
+
+// Shutdown disconnects every client and refuses new ones.
+func (g *Gateway) Shutdown() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.closed = true
+	for c := range g.clients {
+		c.closeGoingAway()
+		g.remove(c)
+	}
+}
+
+func (g *Gateway) add(c *client) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.closed {
+		return false
+	}
+	g.clients[c] = struct{}{}
+	return true
+}
+
+// subscribe adds c to topic. It reports false when c is already at the
+// topic limit.
+func (g *Gateway) subscribe(c *client, topic string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.clients[c]; !ok {
+		return false
+	}
+	if _, ok := c.topics[topic]; ok {
+		return true
+	}
+	if len(c.topics) >= g.cfg.MaxTopics {
+		return false
+	}
+
+	c.topics[topic] = struct{}{}
+	if g.topics[topic] == nil {
+		g.topics[topic] = make(map[*client]struct{})
+	}
+	g.topics[topic][c] = struct{}{}
+	return true
+}
+
+func (g *Gateway) unsubscribe(c *client, topic string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.leave(c, topic)
+}
+
+// leave removes c from topic. g.mu must be held.
+func (g *Gateway) leave(c *client, topic string) {
+	delete(c.topics, topic)
+	if subscribers, ok := g.topics[topic]; ok {
+		delete(subscribers, c)
+		if len(subscribers) == 0 {
+			delete(g.topics, topic)
+		}
+	}
+}
+
+// remove forgets c and all its subscriptions. g.mu must be held.
+func (g *Gateway) remove(c *client) {
+	if _, ok := g.clients[c]; !ok {
+		return
+	}
+	delete(g.clients, c)
+	for topic := range c.topics {
+		g.leave(c, topic)
+	}
+	c.stop()
+}
+
+// evict disconnects a client that cannot keep up. g.mu must be held.
+func (g *Gateway) evict(c *client) {
+	c.closeSlow()
+	g.remove(c)
+}
+
+func (g *Gateway) disconnect(c *client) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.remove(c)
+}
+
+var (
+	defaultGateway *Gateway
+	defaultMu      sync.Mutex
+)
+
+// Default returns the gateway used by the API, created from the socket
+// configuration on first use.
+func Default() *Gateway {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultGateway == nil {
+		defaultGateway = NewGateway(config.Get().Socket)
+	}
+	return defaultGateway
+}
+
+// Publish publishes an event on the default gateway.
+func Publish(topic string, event string, data any) error {
+	return Default().Publish(topic, event, data)
+}
@@ -0,0 +1,46 @@
+package socket
+
+import "encoding/json"
+
+// Message types sent by clients.
+const (
+	TypeSubscribe   = "subscribe"
+	TypeUnsubscribe = "unsubscribe"
+	TypePing        = "ping"
+)
+
+// Message types sent by the server.
+const (
+	TypeAck   = "ack"
+	TypeError = "error"
+	TypeEvent = "event"
+	TypePong  = "pong"
+)
+
+// Request is a message from a client. ID is optional and echoed back in
+// the matching ack, error or pong so clients can correlate replies.
+//
+//	{"type": "subscribe", "topic": "post:<id>", "id": "1"}
+//	{"type": "unsubscribe", "topic": "post:<id>", "id": "2"}
+//	{"type": "ping", "id": "3"}
+type Request struct {
+	Type  string `json:"type"`
+	ID    string `json:"id,omitempty"`
+	Topic string `json:"topic,omitempty"`
+}
+
+// Response is a message from the server. Events carry the topic they were
+// published on, the event name such as post.created, and its data.
+//
+//	{"type": "ack", "id": "1", "topic": "post:<id>"}
+//	{"type": "error", "id": "1", "error": "forbidden"}
+//	{"type": "event", "topic": "posts:new", "event": "post.created", "data": {...}}
+//	{"type": "pong", "id": "3"}
+type Response struct {
+	Type  string          `json:"type"`
+	ID    string          `json:"id,omitempty"`
+	Topic string          `json:"topic,omitempty"`
+	Event string          `json:"event,omitempty"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
@@ -0,0 +1,81 @@
+package socket
+
+import (
+	"api/src/database"
+	"api/src/repositories"
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// TopicNewPosts receives every new post.
+const TopicNewPosts = "posts:new"
+
+var (
+	errUnknownTopic = errors.New("unknown topic")
+	errNotFound     = errors.New("not found")
+)
+
+// PostTopic receives updates and the deletion of one post.
+func PostTopic(postID string) string {
+	return "post:" + postID
+}
+
+// UserPostsTopic receives the posts a user creates, updates and deletes.
+func UserPostsTopic(userID string) string {
+	return "user:" + userID + ":posts"
+}
+
+// authorize checks that userID may subscribe to topic. Posts and profiles
+// are public, so a topic is allowed when what it refers to exists.
+func authorize(ctx context.Context, userID string, topic string) error {
+	if topic == TopicNewPosts {
+		return nil
+	}
+
+	postID, isPost := strings.CutPrefix(topic, "post:")
+	authorID, isUser := strings.CutPrefix(topic, "user:")
+	if isUser {
+		authorID, isUser = strings.CutSuffix(authorID, ":posts")
+	}
+
+	switch {
+	case isPost:
+		if _, err := uuid.Parse(postID); err != nil {
+			return errUnknownTopic
+		}
+	case isUser:
+		if _, err := uuid.Parse(authorID); err != nil {
+			return errUnknownTopic
+		}
+	default:
+		return errUnknownTopic
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		return err
+	}
+
+	if isPost {
+		post, err := repositories.NewPostsRepository(db).FindById(ctx, postID)
+		if err != nil {
+			return err
+		}
+		if post == nil {
+			return errNotFound
+		}
+		return nil
+	}
+
+	user, err := repositories.NewUsersRepository(db).FindById(ctx, authorID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return errNotFound
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"avatars/a.jpg", true},
+		{"a.jpg", true},
+		{"a/b/../c.jpg", true},
+		{"", false},
+		{"../a.jpg", false},
+		{"a/../../b.jpg", false},
+		{"/etc/passwd", false},
+		{".upload-123", false},
+		{"avatars/.hidden", false},
+	}
+
+	dir := t.TempDir()
+	l := &Local{dir: dir}
+	for _, tt := range tests {
+		path, err := l.path(tt.key)
+		if (err == nil) != tt.valid {
+			t.Errorf("path(%q) error = %v, want valid %v", tt.key, err, tt.valid)
+			continue
+		}
+		if err == nil && !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+			t.Errorf("path(%q) = %q, outside %q", tt.key, path, dir)
+		}
+	}
+}
+
+func TestLocalRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	l, err := NewLocal(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const key = "avatars/a.jpg"
+	for _, content := range []string{"first", "second"} {
+		if err := l.Put(ctx, key, strings.NewReader(content), int64(len(content)), "image/jpeg"); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+
+		r, err := l.Open(ctx, key)
+		if err != nil {
+			t.Fatalf("Open: %v", err)
+		}
+		got, err := io.ReadAll(r)
+		r.Close()
+		if err != nil || !bytes.Equal(got, []byte(content)) {
+			t.Fatalf("Open read %q, %v, want %q", got, err, content)
+		}
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "avatars"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Put left %d files behind, want 1", len(entries))
+	}
+
+	if err := l.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := l.Open(ctx, key); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete = %v, want ErrNotFound", err)
+	}
+	if err := l.Delete(ctx, key); err != nil {
+		t.Errorf("Delete of a missing object = %v, want nil", err)
+	}
+}
+
+func TestLocalRejectsInvalidKeys(t *testing.T) {
+	ctx := context.Background()
+	l, err := NewLocal(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const key = "../escape.jpg"
+	if err := l.Put(ctx, key, strings.NewReader("x"), 1, "image/jpeg"); err == nil {
+		t.Error("Put accepted a key outside the directory")
+	}
+	if _, err := l.Open(ctx, key); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Open = %v, want an invalid key error", err)
+	}
+	if err := l.Delete(ctx, key); err == nil {
+		t.Error("Delete accepted a key outside the directory")
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"api/src/config"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 keeps objects in a bucket of an S3-compatible service, such as AWS
+// S3 or a local MinIO.
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+func NewS3(cfg config.MediaConfig) (*S3, error) {
+	endpoint, err := url.Parse(cfg.S3Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3 endpoint: %w", err)
+	}
+
+	lookup := minio.BucketLookupDNS
+	if cfg.S3PathStyle {
+		lookup = minio.BucketLookupPath
+	}
+
+	client, err := minio.New(endpoint.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure:       endpoint.Scheme == "https",
+		Region:       cfg.S3Region,
+		BucketLookup: lookup,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3{client: client, bucket: cfg.S3Bucket}, nil
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	// GetObject is lazy, so Stat first to report missing objects here
+	// rather than on the first read.
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return object, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	return s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// Check verifies that the bucket is reachable with the credentials.
+func (s *S3) Check(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucket)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("bucket %q does not exist", s.bucket)
+	}
+	return nil
+}
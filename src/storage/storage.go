@@ -0,0 +1,69 @@
+// Package storage keeps uploaded files in a configurable backend.
+package storage
+
+import (
+	"api/src/config"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// ErrNotFound is returned by Open when no object has the key.
+var ErrNotFound = errors.New("object not found")
+
+// Storage stores objects by key. Check reports whether the backend is
+// usable, for readiness.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes an object. Deleting a missing object is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	Check(ctx context.Context) error
+}
+
+// New builds the storage selected by cfg.Storage.
+func New(cfg config.MediaConfig) (Storage, error) {
+	switch cfg.Storage {
+	case "local":
+		return NewLocal(cfg.Dir)
+	case "s3":
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("unknown media storage %q", cfg.Storage)
+	}
+}
+
+var (
+	current Storage
+	mu      sync.RWMutex
+)
+
+// Configure makes s the storage returned by Get and used by Check.
+func Configure(s Storage) {
+	mu.Lock()
+	defer mu.Unlock()
+	current = s
+}
+
+// Get returns the configured storage.
+func Get() (Storage, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if current == nil {
+		return nil, fmt.Errorf("media storage is not configured")
+	}
+	return current, nil
+}
+
+// Check reports whether the configured storage is usable.
+func Check(ctx context.Context) error {
+	s, err := Get()
+	if err != nil {
+		return err
+	}
+	return s.Check(ctx)
+}
@@ -0,0 +1,220 @@
+// Package stream fans out live post updates to connected clients and
+// keeps a bounded history so clients can resume after reconnecting.
+package stream
+
+import (
+	"api/src/config"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// Event types.
+const (
+	PostCreated = "post.created"
+	PostUpdated = "post.updated"
+	PostDeleted = "post.deleted"
+)
+
+// ErrTooManyConnections is returned by Subscribe when the user already has
+// the maximum number of open streams.
+var ErrTooManyConnections = errors.New("too many open streams")
+
+// subscriberBuffer is how many events may wait for a slow client before it
+// is disconnected. It can resume from the replay buffer.
+const subscriberBuffer = 64
+
+// Event is a change pushed to clients. IDs increase by one per event.
+type Event struct {
+	ID     uint64
+	Type   string
+	PostID string
+	Data   json.RawMessage
+}
+
+// Subscription receives the events matching what its client watches.
+type Subscription struct {
+	Events <-chan Event
+
+	events chan Event
+	done   chan struct{}
+	userID string
+	watch  map[string]bool
+	hub    *Hub
+}
+
+// Done is closed when the subscription ends, either because it was closed
+// or because the client fell too far behind or the hub shut down.
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Close ends the subscription. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// wants reports whether the client should get event. New posts go to
+// everyone; changes only to clients watching the post.
+func (s *Subscription) wants(event Event) bool {
+	return event.Type == PostCreated || s.watch[event.PostID]
+}
+
+// Hub routes published events to subscriptions.
+type Hub struct {
+	mu         sync.Mutex
+	lastID     uint64
+	history    []Event
+	next       int
+	size       int
+	maxPerUser int
+	perUser    map[string]int
+	subs       map[*Subscription]struct{}
+	closed     bool
+}
+
+// NewHub returns a hub keeping the last replay events and allowing
+// maxPerUser subscriptions per user.
+func NewHub(replay int, maxPerUser int) *Hub {
+	return &Hub{
+		history:    make([]Event, replay),
+		maxPerUser: maxPerUser,
+		perUser:    make(map[string]int),
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish records an event and delivers it to interested subscriptions.
+func (h *Hub) Publish(eventType string, postID string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastID++
+	event := Event{ID: h.lastID, Type: eventType, PostID: postID, Data: payload}
+
+	if len(h.history) > 0 {
+		h.history[h.next] = event
+		h.next = (h.next + 1) % len(h.history)
+		if h.size < len(h.history) {
+			h.size++
+		}
+	}
+
+	for sub := range h.subs {
+		if !sub.wants(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			h.remove(sub)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe opens a subscription for userID receiving new posts and
+// changes to the watched posts. When lastEventID is not nil, the buffered
+// events after it are returned for replay; truncated reports that some
+// of them were already dropped from the buffer.
+func (h *Hub) Subscribe(userID string, watch []string, lastEventID *uint64) (sub *Subscription, replay []Event, truncated bool, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, nil, false, errors.New("stream is shut down")
+	}
+	if h.perUser[userID] >= h.maxPerUser {
+		return nil, nil, false, ErrTooManyConnections
+	}
+
+	events := make(chan Event, subscriberBuffer)
+	sub = &Subscription{
+		Events: events,
+		events: events,
+		done:   make(chan struct{}),
+		userID: userID,
+		watch:  make(map[string]bool, len(watch)),
+		hub:    h,
+	}
+	for _, id := range watch {
+		sub.watch[id] = true
+	}
+
+	// IDs beyond the latest belong to an earlier run of the server and
+	// cannot be resumed.
+	if lastEventID != nil && *lastEventID <= h.lastID {
+		oldest := h.lastID - uint64(h.size) + 1
+		truncated = *lastEventID+1 < oldest
+
+		start := (h.next - h.size + len(h.history)) % max(len(h.history), 1)
+		for i := 0; i < h.size; i++ {
+			event := h.history[(start+i)%len(h.history)]
+			if event.ID > *lastEventID && sub.wants(event) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	h.subs[sub] = struct{}{}
+	h.perUser[userID]++
+
+	return sub, replay, truncated, nil
+}
+
+// Shutdown ends every subscription and refuses new ones.
+func (h *Hub) Shutdown() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		h.remove(sub)
+	}
+}
+
+// remove ends sub. h.mu must be held.
+func (h *Hub) remove(sub *Subscription) {
+	if _, ok := h.subs[sub]; !ok {
+		return
+	}
+
+	delete(h.subs, sub)
+	close(sub.done)
+
+	h.perUser[sub.userID]--
+	if h.perUser[sub.userID] <= 0 {
+		delete(h.perUser, sub.userID)
+	}
+}
+
+var (
+	defaultHub *Hub
+	defaultMu  sync.Mutex
+)
+
+// Default returns the hub used by the API, created from the stream
+// configuration on first use.
+func Default() *Hub {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+
+	if defaultHub == nil {
+		cfg := config.Get().Stream
+		defaultHub = NewHub(cfg.ReplayBuffer, cfg.MaxConnectionsPerUser)
+	}
+	return defaultHub
+}
+
+// Publish publishes an event on the default hub.
+func Publish(eventType string, postID string, data any) error {
+	return Default().Publish(eventType, postID, data)
+}
@@ -0,0 +1,71 @@
+// Package trending runs the job that ranks trending tags.
+package trending
+
+import (
+	"api/src/config"
+	"api/src/database"
+	"api/src/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Refresh recomputes the trending tags of every configured window.
+func Refresh(ctx context.Context, cfg config.TrendingConfig) error {
+	db, err := database.Connect()
+	if err != nil {
+		return err
+	}
+
+	repository := repositories.NewTrendingRepository(db)
+
+	var errs []error
+	for _, window := range cfg.Windows {
+		length, err := time.ParseDuration(window)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("window %s: %w", window, err))
+			continue
+		}
+
+		// Aggregating a whole window can take longer than the statement
+		// timeout requests get.
+		windowCtx, cancel := context.WithTimeout(ctx, cfg.Timeout)
+		refreshed, err := repository.Refresh(windowCtx, window, length, cfg.HalfLife)
+		cancel()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("window %s: %w", window, err))
+			continue
+		}
+		if !refreshed {
+			log.Debug().Str("window", window).Msg("Trending tags already being refreshed elsewhere")
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Run refreshes the trending tags right away and then every
+// cfg.Interval until ctx is done. A zero interval disables it.
+func Run(ctx context.Context, cfg config.TrendingConfig) {
+	if cfg.Interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := Refresh(ctx, cfg); err != nil && ctx.Err() == nil {
+			log.Error().Err(err).Msg("Failed to refresh trending tags")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}